@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"os"
+	"strconv"
 
 	"fyne.io/fyne/v2/app"
 	"github.com/joho/godotenv"
@@ -12,6 +13,10 @@ import (
 )
 
 func main() {
+	if model.ServeWorkerIfRequested() {
+		return
+	}
+
 	err := godotenv.Load()
 	if err != nil {
 		log.Fatalf("Ошибка при загрузке .env файла: %v", err)
@@ -21,17 +26,36 @@ func main() {
 
 	modelPath := getModelPath()
 
-	modelInstance, err := model.NewModel(modelPath)
+	modelInstance, err := newModel(modelPath)
 	if err != nil {
 		log.Fatalf("Ошибка при создании модели: %v", err)
 	}
+	defer modelInstance.Close()
+	if workers, err := strconv.Atoi(os.Getenv("MODEL_BATCH_WORKERS")); err == nil {
+		modelInstance.SetBatchWorkers(workers)
+	}
 
 	viewCalc := view.NewCalculatorView(appInstance)
 
-	presenter := presenter.NewPresenter(viewCalc, modelInstance)
+	presenter := presenter.NewPresenter(viewCalc, modelInstance, presenter.ConfigFromEnv())
 	viewCalc.InitPresenter(presenter)
 
+	if extensionsDir := os.Getenv("EXTENSIONS_DIR"); extensionsDir != "" {
+		presenter.ReportExtensionErrors(modelInstance.LoadExtensions(extensionsDir))
+	}
+
+	// Пересобранный model.so подхватывается без перезапуска приложения
+	if err := modelInstance.Watch(presenter.ReportEngineReload); err != nil {
+		log.Printf("Hot reload of %s is disabled: %v", modelPath, err)
+	}
+
+	// Сеанс прошлого запуска: строка ввода, x и открытые окна
+	viewCalc.RestoreSession()
+
 	appInstance.Run()
+
+	viewCalc.SaveSession()
+	presenter.SaveMemory()
 }
 
 func getModelPath() string {
@@ -41,3 +65,11 @@ func getModelPath() string {
 	}
 	return modelPath
 }
+
+// MODEL_ISOLATION=inprocess загружает ядро прямо в процесс приложения (удобно для отладки)
+func newModel(modelPath string) (*model.Model, error) {
+	if os.Getenv("MODEL_ISOLATION") == "inprocess" {
+		return model.NewModel(modelPath)
+	}
+	return model.NewIsolatedModel(modelPath)
+}
@@ -0,0 +1,37 @@
+// Пакет расширений с децибелами — пример для EXTENSIONS_DIR.
+// Сборка: go build -buildmode=plugin -o ../decibel.so
+package main
+
+import (
+	"errors"
+	"math"
+)
+
+type registerFunc = func(name string, arity int, impl func(...float64) (float64, error), doc string) error
+
+func Register(register registerFunc) error {
+	if err := register("db", 1, toDecibels, "отношение мощностей в децибелах: 10·log10(a)"); err != nil {
+		return err
+	}
+	if err := register("undb", 1, fromDecibels, "отношение мощностей по децибелам: 10^(a/10)"); err != nil {
+		return err
+	}
+	return register("dbsum", 2, sumDecibels, "сумма двух некогерентных источников в дБ")
+}
+
+func toDecibels(args ...float64) (float64, error) {
+	if args[0] <= 0 {
+		return 0, errors.New("ratio must be positive")
+	}
+	return 10 * math.Log10(args[0]), nil
+}
+
+func fromDecibels(args ...float64) (float64, error) {
+	return math.Pow(10, args[0]/10), nil
+}
+
+func sumDecibels(args ...float64) (float64, error) {
+	return 10 * math.Log10(math.Pow(10, args[0]/10)+math.Pow(10, args[1]/10)), nil
+}
+
+func main() {}
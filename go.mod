@@ -4,7 +4,9 @@ go 1.22.2
 
 require (
 	fyne.io/fyne/v2 v2.5.1
+	github.com/fsnotify/fsnotify v1.7.0
 	github.com/joho/godotenv v1.5.1
+	go.etcd.io/bbolt v1.3.11
 	gonum.org/v1/plot v0.15.0
 )
 
@@ -16,7 +18,6 @@ require (
 	github.com/campoy/embedmd v1.0.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/fredbi/uri v1.1.0 // indirect
-	github.com/fsnotify/fsnotify v1.7.0 // indirect
 	github.com/fyne-io/gl-js v0.0.0-20220119005834-d2da28d9ccfe // indirect
 	github.com/fyne-io/glfw-js v0.0.0-20240101223322-6e1efdc71b7a // indirect
 	github.com/fyne-io/image v0.0.0-20220602074514-4956b0afb3d2 // indirect
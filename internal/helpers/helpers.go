@@ -1,6 +1,8 @@
 package helpers
 
 import (
+	"math"
+	"math/big"
 	"strings"
 	"unicode"
 )
@@ -23,7 +25,9 @@ func ReplaceEConstant(expression string) string {
 
 	for i := 0; i < len(runes); i++ {
 		if runes[i] == 'e' {
-			if i > 0 && unicode.IsDigit(runes[i-1]) && i+1 < len(runes) && (runes[i+1] == '+' || runes[i+1] == '-') {
+			// Экспонента 1e+10 и буква e внутри имени функции остаются как есть
+			if i > 0 && unicode.IsDigit(runes[i-1]) && i+1 < len(runes) && (runes[i+1] == '+' || runes[i+1] == '-') ||
+				i > 0 && unicode.IsLetter(runes[i-1]) || i+1 < len(runes) && unicode.IsLetter(runes[i+1]) {
 				result.WriteRune('e')
 			} else {
 				result.WriteString("2.71828182846")
@@ -35,3 +39,68 @@ func ReplaceEConstant(expression string) string {
 
 	return result.String()
 }
+
+// ReplaceIdentifier заменяет name на value только там, где name — отдельное
+// имя, а не часть более длинного (pi, но не pipe).
+func ReplaceIdentifier(expression, name, value string) string {
+	var result strings.Builder
+	for {
+		index := strings.Index(expression, name)
+		if index < 0 {
+			result.WriteString(expression)
+			return result.String()
+		}
+		end := index + len(name)
+		standalone := (index == 0 || !isLetterByte(expression[index-1])) &&
+			(end == len(expression) || !isLetterByte(expression[end]))
+
+		result.WriteString(expression[:index])
+		if standalone {
+			result.WriteString(value)
+		} else {
+			result.WriteString(name)
+		}
+		expression = expression[end:]
+	}
+}
+
+func isLetterByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+func ApproximateFraction(value float64, maxDenominator int64) (*big.Rat, bool) {
+	if math.IsNaN(value) || math.IsInf(value, 0) || maxDenominator < 1 {
+		return nil, false
+	}
+
+	// Подходящие дроби цепной дроби: h(n) = a(n)*h(n-1) + h(n-2)
+	x := math.Abs(value)
+	var prevNum, num int64 = 0, 1
+	var prevDen, den int64 = 1, 0
+	for i := 0; i < 64; i++ {
+		a := math.Floor(x)
+		if a*float64(num)+float64(prevNum) > math.MaxInt64/2 {
+			break
+		}
+		nextNum := int64(a)*num + prevNum
+		nextDen := int64(a)*den + prevDen
+		if nextDen > maxDenominator || nextDen <= 0 {
+			break
+		}
+		prevNum, num = num, nextNum
+		prevDen, den = den, nextDen
+
+		if math.Abs(math.Abs(value)-float64(num)/float64(den)) <= 1e-12*math.Max(1, math.Abs(value)) {
+			if value < 0 {
+				num = -num
+			}
+			return big.NewRat(num, den), true
+		}
+		if x-a == 0 {
+			break
+		}
+		x = 1 / (x - a)
+	}
+
+	return nil, false
+}
@@ -0,0 +1,237 @@
+//go:build !js
+
+package history
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"slices"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var entriesBucket = []byte("entries")
+
+// База открывается на время одной операции: bbolt блокирует файл целиком,
+// и иначе второй запущенный калькулятор не смог бы записать историю
+const dbTimeout = 5 * time.Second
+
+// DB хранит историю во встроенной базе bbolt. Ключ записи — время и номер,
+// поэтому записи упорядочены по времени.
+type DB struct {
+	path      string
+	retention Retention
+}
+
+func NewDB(path string) *DB {
+	return &DB{path: path}
+}
+
+func openDB(path string, retention Retention) (Store, error) {
+	db := NewDB(path)
+	db.SetRetention(retention)
+	return db, nil
+}
+
+func (d *DB) Path() string {
+	return d.path
+}
+
+func (d *DB) SetRetention(retention Retention) {
+	d.retention = retention
+}
+
+func (d *DB) Append(entry Entry) error {
+	if entry.Version == 0 {
+		entry.Version = FormatVersion
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	return d.update(func(bucket *bolt.Bucket) error {
+		// Повтор последней записи переносится под новый ключ с закладкой и заметкой;
+		// для проверки достаточно последнего ключа, всю базу читать не нужно
+		if key, value := bucket.Cursor().Last(); key != nil {
+			var last Entry
+			if json.Unmarshal(value, &last) == nil && sameEntry(last, entry) {
+				entry = appendEntry([]Entry{last}, entry)[0]
+				if err := bucket.Delete(key); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := putEntry(bucket, entry); err != nil {
+			return err
+		}
+		return d.applyRetention(bucket, entry.Time)
+	})
+}
+
+func (d *DB) Entries() ([]Entry, error) {
+	var entries []Entry
+	err := d.view(func(bucket *bolt.Bucket) error {
+		var err error
+		_, entries, err = loadEntries(bucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d.retention.Apply(entries, time.Now()), nil
+}
+
+func (d *DB) SetPinned(entry Entry, pinned bool) error {
+	return d.change(entry, func(e *Entry) { e.Pinned = pinned })
+}
+
+func (d *DB) SetNote(entry Entry, note string) error {
+	return d.change(entry, func(e *Entry) { e.Note = normalizeNote(note) })
+}
+
+func (d *DB) change(entry Entry, change func(*Entry)) error {
+	return d.update(func(bucket *bolt.Bucket) error {
+		keys, entries, err := loadEntries(bucket)
+		if err != nil {
+			return err
+		}
+		if err := updateEntries(entries, entry, change); err != nil {
+			return err
+		}
+		for i, e := range entries {
+			if isStored(e, entry) {
+				if err := bucket.Put(keys[i], marshalEntry(e)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (d *DB) Merge(entries []Entry) (int, error) {
+	added := 0
+	err := d.update(func(bucket *bolt.Bucket) error {
+		_, existing, err := loadEntries(bucket)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if slices.ContainsFunc(existing, func(e Entry) bool { return isStored(e, entry) }) {
+				continue
+			}
+			if err := putEntry(bucket, entry); err != nil {
+				return err
+			}
+			existing = append(existing, entry)
+			added++
+		}
+		return d.applyRetention(bucket, time.Now())
+	})
+	return added, err
+}
+
+func (d *DB) Clear() error {
+	return d.update(func(bucket *bolt.Bucket) error {
+		keys, _, err := loadEntries(bucket)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Watch сообщает об изменениях файла базы, в том числе другим экземпляром программы.
+func (d *DB) Watch(onChange func()) (func(), error) {
+	return watchPath(d.path, onChange)
+}
+
+func (d *DB) view(fn func(*bolt.Bucket) error) error {
+	db, err := bolt.Open(d.path, 0644, &bolt.Options{Timeout: dbTimeout})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(entriesBucket)
+		if bucket == nil {
+			return nil
+		}
+		return fn(bucket)
+	})
+}
+
+func (d *DB) update(fn func(*bolt.Bucket) error) error {
+	db, err := bolt.Open(d.path, 0644, &bolt.Options{Timeout: dbTimeout})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(entriesBucket)
+		if err != nil {
+			return err
+		}
+		return fn(bucket)
+	})
+}
+
+// applyRetention удаляет записи сверх ограничений. Без ограничений база
+// не читается, и запись новой строки не зависит от размера истории.
+func (d *DB) applyRetention(bucket *bolt.Bucket, now time.Time) error {
+	if !d.retention.limited() {
+		return nil
+	}
+	keys, entries, err := loadEntries(bucket)
+	if err != nil {
+		return err
+	}
+	for i, keep := range d.retention.keep(entries, now) {
+		if !keep {
+			if err := bucket.Delete(keys[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// loadEntries читает записи в порядке ключей; поврежденные записи пропускаются.
+func loadEntries(bucket *bolt.Bucket) ([][]byte, []Entry, error) {
+	var keys [][]byte
+	var entries []Entry
+	err := bucket.ForEach(func(key, value []byte) error {
+		var entry Entry
+		if err := json.Unmarshal(value, &entry); err != nil {
+			return nil
+		}
+		keys = append(keys, append([]byte(nil), key...))
+		entries = append(entries, entry)
+		return nil
+	})
+	return keys, entries, err
+}
+
+// Ключ — время записи и номер в базе: записи с одним временем не затирают друг друга
+func putEntry(bucket *bolt.Bucket, entry Entry) error {
+	sequence, err := bucket.NextSequence()
+	if err != nil {
+		return err
+	}
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(entry.Time.UnixNano()))
+	binary.BigEndian.PutUint64(key[8:], sequence)
+	return bucket.Put(key, marshalEntry(entry))
+}
+
+func marshalEntry(entry Entry) []byte {
+	value, _ := json.Marshal(entry)
+	return value
+}
@@ -0,0 +1,8 @@
+package history
+
+import "errors"
+
+// bbolt использует mmap и flock, в браузере база недоступна
+func openDB(string, Retention) (Store, error) {
+	return nil, errors.New("the bolt history backend is not supported on js")
+}
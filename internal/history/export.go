@@ -0,0 +1,212 @@
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportFormat — формат выгрузки истории.
+type ExportFormat string
+
+const (
+	ExportJSONLines ExportFormat = "jsonl"
+	ExportCSV       ExportFormat = "csv"
+	ExportJSON      ExportFormat = "json"
+	ExportMarkdown  ExportFormat = "md"
+)
+
+var csvHeader = []string{"time", "kind", "expression", "result", "x", "pinned", "note"}
+
+// FormatFromPath выбирает формат по расширению файла; неизвестное
+// расширение — формат файла истории.
+func FormatFromPath(path string) ExportFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return ExportCSV
+	case ".json":
+		return ExportJSON
+	case ".md", ".markdown":
+		return ExportMarkdown
+	}
+	return ExportJSONLines
+}
+
+// Export записывает записи в выбранном формате: время, тип, выражение,
+// результат, x, закладка и заметка.
+func Export(w io.Writer, entries []Entry, format ExportFormat) error {
+	switch format {
+	case ExportJSONLines:
+		return WriteEntries(w, entries)
+	case ExportJSON:
+		if entries == nil {
+			entries = []Entry{}
+		}
+		content, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(content, '\n'))
+		return err
+	case ExportCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(csvHeader); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := writer.Write(csvRecord(entry)); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	case ExportMarkdown:
+		return writeMarkdown(w, entries)
+	}
+	return fmt.Errorf("unknown history export format %q", format)
+}
+
+func csvRecord(entry Entry) []string {
+	return []string{
+		entry.Time.Format(time.RFC3339Nano),
+		string(entry.Kind),
+		entry.Expression,
+		entry.Result,
+		entry.X,
+		strconv.FormatBool(entry.Pinned),
+		entry.Note,
+	}
+}
+
+func writeMarkdown(w io.Writer, entries []Entry) error {
+	escape := strings.NewReplacer("|", `\|`, "\n", " ")
+	lines := []string{
+		"| Time | Kind | Expression | Result | x | Pinned | Note |",
+		"| --- | --- | --- | --- | --- | --- | --- |",
+	}
+	for _, entry := range entries {
+		pinned := ""
+		if entry.Pinned {
+			pinned = "yes"
+		}
+		cells := []string{
+			entry.Time.Local().Format("2006-01-02 15:04:05"),
+			string(entry.Kind),
+			"`" + entry.Expression + "`",
+			entry.Result,
+			entry.X,
+			pinned,
+			entry.Note,
+		}
+		for i, cell := range cells {
+			cells[i] = escape.Replace(cell)
+		}
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// ReadFile читает историю другого экземпляра программы: файл истории
+// (в том числе старого формата) или выгрузку CSV и JSON.
+func ReadFile(path string) ([]Entry, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch FormatFromPath(path) {
+	case ExportCSV:
+		return readCSV(content)
+	case ExportJSON:
+		var entries []Entry
+		if err := json.Unmarshal(content, &entries); err != nil {
+			return nil, fmt.Errorf("invalid history JSON: %w", err)
+		}
+		return normalize(entries), nil
+	case ExportMarkdown:
+		return nil, errors.New("Markdown history cannot be imported")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, _ := parseEntries(content, info.ModTime())
+	return normalize(entries), nil
+}
+
+func readCSV(content []byte) ([]Entry, error) {
+	records, err := csv.NewReader(strings.NewReader(string(content))).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid history CSV: %w", err)
+	}
+	if len(records) == 0 || !slices.Equal(records[0], csvHeader) {
+		return nil, errors.New("invalid history CSV: unexpected header")
+	}
+
+	var entries []Entry
+	for i, record := range records[1:] {
+		at, err := time.Parse(time.RFC3339Nano, record[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid history CSV line %d: %w", i+2, err)
+		}
+		pinned, _ := strconv.ParseBool(record[5])
+		entries = append(entries, Entry{
+			Time:       at,
+			Kind:       Kind(record[1]),
+			Expression: record[2],
+			Result:     record[3],
+			X:          record[4],
+			Pinned:     pinned,
+			Note:       record[6],
+		})
+	}
+	return normalize(entries), nil
+}
+
+// Записи без версии и типа (ручная правка, чужая выгрузка) — вычисления текущего формата
+func normalize(entries []Entry) []Entry {
+	var result []Entry
+	for _, entry := range entries {
+		if entry.Expression == "" {
+			continue
+		}
+		entry.Version = FormatVersion
+		if entry.Kind == "" {
+			entry.Kind = KindExpression
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+// Merge добавляет записи в историю, пропуская уже сохраненные (то же время
+// и содержимое), и упорядочивает историю по времени. Возвращает число
+// добавленных записей.
+func (f *File) Merge(entries []Entry) (int, error) {
+	unlock, err := f.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	existing, err := f.read()
+	if err != nil {
+		return 0, err
+	}
+
+	merged, added := mergeEntries(existing, entries)
+	if added == 0 {
+		return 0, nil
+	}
+	return added, f.write(f.retention.Apply(merged, time.Now()))
+}
@@ -0,0 +1,96 @@
+package history
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Filter отбирает записи истории. Пустые поля не ограничивают выборку;
+// To не включается в диапазон, Pinned оставляет только закрепленные записи.
+type Filter struct {
+	Query  string
+	Regex  bool
+	From   time.Time
+	To     time.Time
+	Kinds  []Kind
+	Pinned bool
+}
+
+// Group — записи одного дня.
+type Group struct {
+	Day     time.Time
+	Entries []Entry
+}
+
+// Apply возвращает подходящие записи в исходном порядке; текст ищется в выражении,
+// результате и заметке. Ошибка — неверное регулярное выражение.
+func (f Filter) Apply(entries []Entry) ([]Entry, error) {
+	match, err := f.matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Entry
+	for _, entry := range entries {
+		if len(f.Kinds) > 0 && !slices.Contains(f.Kinds, entry.Kind) {
+			continue
+		}
+		if f.Pinned && !entry.Pinned {
+			continue
+		}
+		if !f.From.IsZero() && entry.Time.Before(f.From) {
+			continue
+		}
+		if !f.To.IsZero() && !entry.Time.Before(f.To) {
+			continue
+		}
+		if match(entry.Expression) || match(entry.Result) || match(entry.Note) {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
+
+// Поиск подстроки не учитывает регистр; регулярное выражение применяется как есть
+func (f Filter) matcher() (func(string) bool, error) {
+	if f.Query == "" {
+		return func(string) bool { return true }, nil
+	}
+	if f.Regex {
+		re, err := regexp.Compile(f.Query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid search pattern: %w", err)
+		}
+		return re.MatchString, nil
+	}
+	query := strings.ToLower(f.Query)
+	return func(text string) bool {
+		return strings.Contains(strings.ToLower(text), query)
+	}, nil
+}
+
+// GroupByDay раскладывает записи по дням (местное время): новые дни первыми,
+// внутри дня записи идут в исходном порядке.
+func GroupByDay(entries []Entry) []Group {
+	var groups []Group
+	index := map[time.Time]int{}
+	for _, entry := range entries {
+		t := entry.Time.Local()
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+		i, ok := index[day]
+		if !ok {
+			i = len(groups)
+			index[day] = i
+			groups = append(groups, Group{Day: day})
+		}
+		groups[i].Entries = append(groups[i].Entries, entry)
+	}
+
+	slices.SortStableFunc(groups, func(a, b Group) int {
+		return b.Day.Compare(a.Day)
+	})
+	return groups
+}
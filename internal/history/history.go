@@ -0,0 +1,268 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FormatVersion — версия формата записи; старые текстовые файлы считаются версией 0.
+const FormatVersion = 1
+
+type Kind string
+
+const (
+	KindExpression Kind = "expression"
+	KindPlot       Kind = "plot"
+	KindCredit     Kind = "credit"
+)
+
+// Mode — настройки калькулятора в момент вычисления.
+type Mode struct {
+	Exact      bool   `json:"exact,omitempty"`
+	Fraction   bool   `json:"fraction,omitempty"`
+	Scientific bool   `json:"scientific,omitempty"`
+	Percent    string `json:"percent,omitempty"`
+}
+
+type Entry struct {
+	Version    int       `json:"v"`
+	Time       time.Time `json:"time"`
+	Kind       Kind      `json:"kind"`
+	Expression string    `json:"expression"`
+	Result     string    `json:"result,omitempty"`
+	X          string    `json:"x,omitempty"`
+	Mode       Mode      `json:"mode"`
+	Pinned     bool      `json:"pinned,omitempty"`
+	Note       string    `json:"note,omitempty"`
+
+	// Время записи старого формата — дата изменения файла, а не вычисления
+	fileTime bool
+}
+
+// File хранит историю в файле по записи JSON на строку. Изменения файла
+// защищены блокировкой, поэтому его могут разделять несколько запущенных
+// калькуляторов.
+type File struct {
+	path      string
+	retention Retention
+}
+
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+func (f *File) Path() string {
+	return f.path
+}
+
+func (f *File) SetRetention(retention Retention) {
+	f.retention = retention
+}
+
+// Append дописывает запись в конец файла. Повтор последней записи заменяет ее,
+// а записи сверх Retention удаляются; в этих случаях файл перезаписывается.
+// Файл старого формата перед этим переводится в JSON.
+func (f *File) Append(entry Entry) error {
+	if entry.Version == 0 {
+		entry.Version = FormatVersion
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	unlock, err := f.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := f.read()
+	if err != nil {
+		return err
+	}
+	if n := len(entries); n > 0 && sameEntry(entries[n-1], entry) {
+		return f.write(f.retention.Apply(appendEntry(entries, entry), entry.Time))
+	}
+	entries = append(entries, entry)
+	if kept := f.retention.Apply(entries, entry.Time); len(kept) != len(entries) {
+		return f.write(kept)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// Entries читает записи в порядке добавления. Отсутствующий файл — пустая история.
+func (f *File) Entries() ([]Entry, error) {
+	if _, err := os.Stat(f.path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	unlock, err := f.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	entries, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	return f.retention.Apply(entries, time.Now()), nil
+}
+
+// SetPinned закрепляет запись или снимает закрепление.
+func (f *File) SetPinned(entry Entry, pinned bool) error {
+	return f.update(entry, func(e *Entry) { e.Pinned = pinned })
+}
+
+// SetNote сохраняет заметку к записи; пустая строка удаляет заметку.
+func (f *File) SetNote(entry Entry, note string) error {
+	return f.update(entry, func(e *Entry) { e.Note = normalizeNote(note) })
+}
+
+// update изменяет запись, найденную по времени и содержимому, и перезаписывает файл.
+func (f *File) update(entry Entry, change func(*Entry)) error {
+	unlock, err := f.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := f.read()
+	if err != nil {
+		return err
+	}
+	if err := updateEntries(entries, entry, change); err != nil {
+		return err
+	}
+	return f.write(entries)
+}
+
+func normalizeNote(note string) string {
+	return strings.TrimSpace(note)
+}
+
+func (f *File) read() ([]Entry, error) {
+	content, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, legacy := parseEntries(content, info.ModTime())
+	if legacy {
+		if err := f.write(entries); err != nil {
+			return nil, err
+		}
+		log.Printf("History file '%s' migrated to format version %d", f.path, FormatVersion)
+	}
+	return entries, nil
+}
+
+func (f *File) Clear() error {
+	unlock, err := f.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return f.write(nil)
+}
+
+// parseEntries разбирает строки файла; строки старого формата (одно
+// выражение на строку) становятся записями с временем изменения файла.
+func parseEntries(content []byte, legacyTime time.Time) (entries []Entry, legacy bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(line, "{") {
+			legacy = true
+			entries = append(entries, Entry{
+				Version:    FormatVersion,
+				Time:       legacyTime,
+				Kind:       KindExpression,
+				Expression: line,
+				fileTime:   true,
+			})
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			log.Printf("Skipping damaged history entry: %v", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, legacy
+}
+
+// WriteEntries записывает записи в формате файла истории: по JSON на строку.
+func WriteEntries(w io.Writer, entries []Entry) error {
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write атомарно перезаписывает файл: через временный файл и rename.
+func (f *File) write(entries []Entry) error {
+	var content bytes.Buffer
+	if err := WriteEntries(&content, entries); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(content.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
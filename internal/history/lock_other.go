@@ -0,0 +1,8 @@
+//go:build !unix
+
+package history
+
+// На системах без flock запись защищена только атомарной заменой файла
+func (f *File) lock() (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,86 @@
+package history
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Retention ограничивает размер истории; нулевые поля — без ограничения.
+type Retention struct {
+	MaxEntries int
+	MaxAge     time.Duration
+}
+
+// Apply оставляет не больше MaxEntries последних записей не старше MaxAge.
+// Закрепленные записи не удаляются и в MaxEntries не учитываются.
+func (r Retention) Apply(entries []Entry, now time.Time) []Entry {
+	var kept []Entry
+	for i, keep := range r.keep(entries, now) {
+		if keep {
+			kept = append(kept, entries[i])
+		}
+	}
+	return kept
+}
+
+func (r Retention) limited() bool {
+	return r.MaxEntries > 0 || r.MaxAge > 0
+}
+
+// keep отмечает записи, которые остаются после применения ограничений.
+func (r Retention) keep(entries []Entry, now time.Time) []bool {
+	cutoff := now.Add(-r.MaxAge)
+	expired := func(entry Entry) bool {
+		return r.MaxAge > 0 && entry.Time.Before(cutoff)
+	}
+
+	unpinned := 0
+	for _, entry := range entries {
+		if !entry.Pinned && !expired(entry) {
+			unpinned++
+		}
+	}
+	drop := 0
+	if r.MaxEntries > 0 && unpinned > r.MaxEntries {
+		drop = unpinned - r.MaxEntries
+	}
+
+	result := make([]bool, len(entries))
+	for i, entry := range entries {
+		switch {
+		case entry.Pinned:
+		case expired(entry):
+			continue
+		case drop > 0:
+			drop--
+			continue
+		}
+		result[i] = true
+	}
+	return result
+}
+
+// ParseAge разбирает срок хранения: длительность Go (720h) или число дней (30d).
+func ParseAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid history age %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid history age %q", value)
+	}
+	return age, nil
+}
+
+// sameEntry — повтор того же вычисления подряд: такие записи схлопываются в одну.
+// Закладка и заметка при этом сохраняются.
+func sameEntry(a, b Entry) bool {
+	return a.Kind == b.Kind && a.Expression == b.Expression && a.Result == b.Result && a.X == b.X
+}
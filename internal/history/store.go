@@ -0,0 +1,191 @@
+package history
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Store — хранилище истории. Реализации: File (JSON по строкам), DB (bbolt)
+// и Memory (без сохранения, для тестов).
+type Store interface {
+	Append(entry Entry) error
+	Entries() ([]Entry, error)
+	SetPinned(entry Entry, pinned bool) error
+	SetNote(entry Entry, note string) error
+	Merge(entries []Entry) (int, error)
+	Clear() error
+	Watch(onChange func()) (func(), error)
+}
+
+const (
+	BackendFile = "file"
+	BackendBolt = "bolt"
+)
+
+// OpenStore создает хранилище выбранного типа; пустой тип — файл.
+func OpenStore(backend, path string, retention Retention) (Store, error) {
+	switch backend {
+	case "", BackendFile:
+		file := NewFile(path)
+		file.SetRetention(retention)
+		return file, nil
+	case BackendBolt:
+		return openDB(path, retention)
+	}
+	return nil, fmt.Errorf("unknown history backend %q", backend)
+}
+
+// Memory хранит историю в памяти процесса.
+type Memory struct {
+	mu        sync.Mutex
+	entries   []Entry
+	retention Retention
+	watchers  map[int]func()
+	nextWatch int
+}
+
+func NewMemory() *Memory {
+	return &Memory{watchers: map[int]func(){}}
+}
+
+func (m *Memory) SetRetention(retention Retention) {
+	m.retention = retention
+}
+
+func (m *Memory) Append(entry Entry) error {
+	if entry.Version == 0 {
+		entry.Version = FormatVersion
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	m.mu.Lock()
+	m.entries = appendEntry(m.entries, entry)
+	m.entries = m.retention.Apply(m.entries, entry.Time)
+	m.mu.Unlock()
+	m.notify()
+	return nil
+}
+
+func (m *Memory) Entries() ([]Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.retention.Apply(slices.Clone(m.entries), time.Now()), nil
+}
+
+func (m *Memory) SetPinned(entry Entry, pinned bool) error {
+	return m.update(entry, func(e *Entry) { e.Pinned = pinned })
+}
+
+func (m *Memory) SetNote(entry Entry, note string) error {
+	return m.update(entry, func(e *Entry) { e.Note = normalizeNote(note) })
+}
+
+func (m *Memory) update(entry Entry, change func(*Entry)) error {
+	m.mu.Lock()
+	err := updateEntries(m.entries, entry, change)
+	m.mu.Unlock()
+	if err == nil {
+		m.notify()
+	}
+	return err
+}
+
+func (m *Memory) Merge(entries []Entry) (int, error) {
+	m.mu.Lock()
+	merged, added := mergeEntries(m.entries, entries)
+	m.entries = m.retention.Apply(merged, time.Now())
+	m.mu.Unlock()
+	if added > 0 {
+		m.notify()
+	}
+	return added, nil
+}
+
+func (m *Memory) Clear() error {
+	m.mu.Lock()
+	m.entries = nil
+	m.mu.Unlock()
+	m.notify()
+	return nil
+}
+
+func (m *Memory) Watch(onChange func()) (func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := m.nextWatch
+	m.nextWatch++
+	m.watchers[id] = onChange
+	return func() {
+		m.mu.Lock()
+		delete(m.watchers, id)
+		m.mu.Unlock()
+	}, nil
+}
+
+func (m *Memory) notify() {
+	m.mu.Lock()
+	watchers := make([]func(), 0, len(m.watchers))
+	for _, onChange := range m.watchers {
+		watchers = append(watchers, onChange)
+	}
+	m.mu.Unlock()
+	for _, onChange := range watchers {
+		onChange()
+	}
+}
+
+// appendEntry добавляет запись; повтор последней записи заменяет ее,
+// сохраняя закладку и заметку.
+func appendEntry(entries []Entry, entry Entry) []Entry {
+	n := len(entries)
+	if n == 0 || !sameEntry(entries[n-1], entry) {
+		return append(entries, entry)
+	}
+	entry.Pinned = entry.Pinned || entries[n-1].Pinned
+	if entry.Note == "" {
+		entry.Note = entries[n-1].Note
+	}
+	entries[n-1] = entry
+	return entries
+}
+
+// updateEntries изменяет записи с тем же временем и содержимым, что и entry.
+func updateEntries(entries []Entry, entry Entry, change func(*Entry)) error {
+	found := false
+	for i := range entries {
+		if isStored(entries[i], entry) {
+			change(&entries[i])
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("history entry %q not found", entry.Expression)
+	}
+	return nil
+}
+
+// mergeEntries добавляет новые записи и упорядочивает историю по времени.
+func mergeEntries(existing, entries []Entry) ([]Entry, int) {
+	added := 0
+	for _, entry := range entries {
+		if !slices.ContainsFunc(existing, func(e Entry) bool { return isStored(e, entry) }) {
+			existing = append(existing, entry)
+			added++
+		}
+	}
+	slices.SortStableFunc(existing, func(a, b Entry) int {
+		return a.Time.Compare(b.Time)
+	})
+	return existing, added
+}
+
+// isStored — та же запись: время и содержимое совпадают. У записей старого
+// формата время меняется вместе с датой файла, поэтому сравнивается только
+// содержимое — иначе повторный импорт того же файла дублировал бы записи.
+func isStored(stored, entry Entry) bool {
+	return (entry.fileTime || stored.Time.Equal(entry.Time)) && sameEntry(stored, entry)
+}
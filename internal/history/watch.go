@@ -0,0 +1,79 @@
+package history
+
+import (
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Перезапись файла — несколько событий подряд (временный файл, rename, запись
+// страниц базы), уведомляем один раз
+const watchDelay = 100 * time.Millisecond
+
+// Watch вызывает onChange после каждого изменения файла истории, в том числе
+// другим экземпляром программы. Возвращаемая функция прекращает наблюдение.
+func (f *File) Watch(onChange func()) (func(), error) {
+	return watchPath(f.path, onChange)
+}
+
+func watchPath(path string, onChange func()) (func(), error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// Следим за каталогом: write заменяет файл новым
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	var timer *time.Timer
+	stopped := false
+	path = filepath.Clean(path)
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != path || event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) == 0 {
+					continue
+				}
+				mu.Lock()
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(watchDelay, func() {
+					mu.Lock()
+					skip := stopped
+					mu.Unlock()
+					if !skip {
+						onChange()
+					}
+				})
+				mu.Unlock()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("History watcher error: %v", err)
+			}
+		}
+	}()
+
+	return func() {
+		mu.Lock()
+		stopped = true
+		if timer != nil {
+			timer.Stop()
+		}
+		mu.Unlock()
+		watcher.Close()
+	}, nil
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// SetBatchWorkers задает число горутин, между которыми делится EvaluateBatch.
+func (m *Model) SetBatchWorkers(n int) {
+	m.batchWorkers = n
+}
+
+// EvaluateBatch вычисляет выражение для каждого значения x.
+func (m *Model) EvaluateBatch(expression string, xs []float64) ([]float64, []error) {
+	return m.EvaluateBatchContext(context.Background(), expression, xs)
+}
+
+func (m *Model) EvaluateBatchContext(ctx context.Context, expression string, xs []float64) ([]float64, []error) {
+	results := make([]float64, len(xs))
+	errs := make([]error, len(xs))
+
+	tokens, err := Tokenize(expression)
+	if err == nil {
+		err = m.Limits().checkTokens(tokens)
+	}
+	if err == nil {
+		err = m.Limits().CheckIterations(len(xs))
+	}
+	eng := m.currentEngine()
+	if err == nil && (eng == nil || eng.calculateContext == nil) {
+		err = fmt.Errorf("plugin not loaded or Calculate function not set")
+	}
+	if err == nil {
+		err = eng.capabilities.checkSupported(tokens)
+	}
+	if err != nil {
+		fillErrors(errs, err)
+		return results, errs
+	}
+
+	// Один вызов ядра на горутину: график или столбец таблицы при одной
+	// горутине уходит в ядро целиком. Отмену ctx вызов ядра учитывает сам.
+	workers := min(max(m.batchWorkers, 1), max(len(xs), 1))
+	chunkSize := (len(xs) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for start := 0; start < len(xs); start += chunkSize {
+		end := min(start+chunkSize, len(xs))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.evaluateChunk(ctx, eng, expression, tokens, xs[start:end], results[start:end], errs[start:end])
+		}()
+	}
+	wg.Wait()
+
+	return results, errs
+}
+
+func (m *Model) evaluateChunk(ctx context.Context, eng *engine, expression string, tokens []Token, xs, results []float64, errs []error) {
+	if err := ctx.Err(); err != nil {
+		fillErrors(errs, err)
+		return
+	}
+
+	if eng.calculateBatch != nil && !m.hasFunctionCalls(tokens) {
+		values, valueErrs := eng.calculateBatch(ctx, expression, xs)
+		copy(results, values)
+		copy(errs, valueErrs)
+		return
+	}
+
+	// Старые плагины без CalculateBatch и выражения с функциями расширений:
+	// по одному вызову ядра на точку
+	for i, x := range xs {
+		if err := ctx.Err(); err != nil {
+			fillErrors(errs[i:], err)
+			return
+		}
+		vars := map[string]float64{"x": x}
+		resolved, err := m.resolve(ctx, eng, tokens, vars)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		results[i], errs[i] = eng.calculateVars(ctx, resolved, vars)
+	}
+}
+
+func fillErrors(errs []error, err error) {
+	for i := range errs {
+		errs[i] = err
+	}
+}
+
+func errorStrings(errs []error) []string {
+	result := make([]string, len(errs))
+	for i, err := range errs {
+		if err != nil {
+			result[i] = err.Error()
+		}
+	}
+	return result
+}
+
+func stringErrors(messages []string, n int) []error {
+	result := make([]error, n)
+	for i := range result {
+		if i < len(messages) && messages[i] != "" {
+			result[i] = errors.New(messages[i])
+		}
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+package model
+
+import (
+	"fmt"
+	"log"
+	"plugin"
+	"slices"
+)
+
+const (
+	ModeCredit = "credit"
+	ModeBatch  = "batch"
+)
+
+const unknownVersion = "unknown"
+
+// Capabilities описывает, что умеет загруженное ядро.
+type Capabilities struct {
+	Functions []string
+	Operators []string
+	Modes     []string
+}
+
+// Ядра без Capabilities() считаются классическими: весь набор функций и кредитный калькулятор
+var defaultCapabilities = Capabilities{
+	Functions: []string{"sqrt", "ln", "log", "sin", "cos", "tan", "asin", "acos", "atan"},
+	Operators: []string{"+", "-", "*", "/", "^", "%"},
+	Modes:     []string{ModeCredit},
+}
+
+func (c Capabilities) HasFunction(name string) bool {
+	return slices.Contains(c.Functions, name)
+}
+
+func (c Capabilities) HasOperator(op string) bool {
+	return slices.Contains(c.Operators, op)
+}
+
+func (c Capabilities) HasMode(mode string) bool {
+	return slices.Contains(c.Modes, mode)
+}
+
+func (m *Model) Version() string {
+	if eng := m.currentEngine(); eng != nil {
+		return eng.version
+	}
+	return unknownVersion
+}
+
+func (m *Model) Capabilities() Capabilities {
+	if eng := m.currentEngine(); eng != nil {
+		return eng.capabilities
+	}
+	return Capabilities{}
+}
+
+// checkSupported отклоняет выражение с функциями и операторами, которых нет в ядре.
+func (c Capabilities) checkSupported(tokens []Token) error {
+	for i, tok := range tokens {
+		switch tok.Kind {
+		case TokenOperator:
+			if !c.HasOperator(tok.Text) {
+				return fmt.Errorf("operator %s is not supported by the engine", tok.Text)
+			}
+		case TokenIdent:
+			name, ok := functionAliases[tok.Text]
+			if ok && i+1 < len(tokens) && tokens[i+1].Kind == TokenLParen && !c.HasFunction(name) {
+				return fmt.Errorf("function %s is not supported by the engine", name)
+			}
+		}
+	}
+	return nil
+}
+
+// discoverCapabilities читает необязательные Version() и Capabilities() плагина
+// и сверяет заявленные режимы с реально найденными символами.
+func discoverCapabilities(plug *plugin.Plugin, eng *engine) {
+	eng.version = unknownVersion
+	if sym, err := plug.Lookup("Version"); err == nil {
+		if version, ok := sym.(func() string); ok {
+			eng.version = version()
+		} else {
+			log.Printf("Ignoring Version with unexpected type %T", sym)
+		}
+	}
+
+	eng.capabilities = defaultCapabilities
+	if sym, err := plug.Lookup("Capabilities"); err == nil {
+		if capabilities, ok := sym.(func() map[string][]string); ok {
+			declared := capabilities()
+			eng.capabilities = Capabilities{
+				Functions: declared["functions"],
+				Operators: declared["operators"],
+				Modes:     declared["modes"],
+			}
+		} else {
+			log.Printf("Ignoring Capabilities with unexpected type %T", sym)
+		}
+	}
+
+	var modes []string
+	for _, mode := range eng.capabilities.Modes {
+		if mode == ModeCredit && (eng.creditAnnuity == nil || eng.creditDifferentiated == nil) {
+			continue
+		}
+		if mode != ModeBatch {
+			modes = append(modes, mode)
+		}
+	}
+	if eng.calculateBatch != nil {
+		modes = append(modes, ModeBatch)
+	}
+	eng.capabilities.Modes = modes
+}
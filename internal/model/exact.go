@@ -0,0 +1,151 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const maxExactExponent = 1024
+
+// Предел размера числителя и знаменателя: большие числа точно не считаются,
+// иначе ((10^1000)^1000)^1000 надолго занял бы процессор и память
+const maxExactBits = 1 << 16
+
+var ErrNotExact = errors.New("expression cannot be evaluated exactly")
+
+// CalculateExact вычисляет выражение в рациональных числах. Выражения без
+// точного значения или со слишком большими числами дают ErrNotExact.
+func (m *Model) CalculateExact(ctx context.Context, expression string, x string) (*big.Rat, error) {
+	if err := m.Limits().checkExpression(expression); err != nil {
+		return nil, err
+	}
+	tree, err := parse(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	var xValue *big.Rat
+	if x = strings.TrimSpace(x); x != "" {
+		var ok bool
+		if xValue, ok = new(big.Rat).SetString(x); !ok {
+			return nil, ErrNotExact
+		}
+	}
+
+	return evalExact(ctx, tree, xValue)
+}
+
+func evalExact(ctx context.Context, n node, x *big.Rat) (*big.Rat, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	switch n := n.(type) {
+	case *numberNode:
+		value, ok := new(big.Rat).SetString(n.Text)
+		if !ok {
+			return nil, fmt.Errorf("invalid number %q", n.Text)
+		}
+		return value, nil
+	case *identNode:
+		switch n.Name {
+		case "x":
+			if x == nil {
+				return nil, errors.New("value of x is not set")
+			}
+			return new(big.Rat).Set(x), nil
+		case "pi", "e":
+			return nil, ErrNotExact
+		}
+		return nil, fmt.Errorf("unknown identifier %q", n.Name)
+	case *callNode:
+		// Трансцендентные функции считаются только в плавающей точке
+		return nil, ErrNotExact
+	case *unaryNode:
+		operand, err := evalExact(ctx, n.Operand, x)
+		if err != nil {
+			return nil, err
+		}
+		if n.Op == "-" {
+			operand.Neg(operand)
+		}
+		return operand, nil
+	case *binaryNode:
+		left, err := evalExact(ctx, n.Left, x)
+		if err != nil {
+			return nil, err
+		}
+		right, err := evalExact(ctx, n.Right, x)
+		if err != nil {
+			return nil, err
+		}
+		result, err := applyExact(n.Op, left, right)
+		if err == nil && (result.Num().BitLen() > maxExactBits || result.Denom().BitLen() > maxExactBits) {
+			return nil, ErrNotExact
+		}
+		return result, err
+	}
+	return nil, fmt.Errorf("unsupported expression node %T", n)
+}
+
+func applyExact(op string, left, right *big.Rat) (*big.Rat, error) {
+	result := new(big.Rat)
+	switch op {
+	case "+":
+		return result.Add(left, right), nil
+	case "-":
+		return result.Sub(left, right), nil
+	case "*":
+		return result.Mul(left, right), nil
+	case "/":
+		if right.Sign() == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return result.Quo(left, right), nil
+	case "%":
+		if right.Sign() == 0 {
+			return nil, errors.New("division by zero")
+		}
+		// Как fmod: a - b*trunc(a/b)
+		quotient := new(big.Int).Quo(
+			new(big.Int).Mul(left.Num(), right.Denom()),
+			new(big.Int).Mul(left.Denom(), right.Num()),
+		)
+		return result.Sub(left, new(big.Rat).Mul(right, new(big.Rat).SetInt(quotient))), nil
+	case "^":
+		return powExact(left, right)
+	}
+	return nil, fmt.Errorf("unsupported operator %q", op)
+}
+
+func powExact(base, exponent *big.Rat) (*big.Rat, error) {
+	if !exponent.IsInt() || !exponent.Num().IsInt64() {
+		return nil, ErrNotExact
+	}
+	power := exponent.Num().Int64()
+	if power > maxExactExponent || power < -maxExactExponent {
+		return nil, ErrNotExact
+	}
+
+	negative := power < 0
+	if negative {
+		if base.Sign() == 0 {
+			return nil, errors.New("division by zero")
+		}
+		power = -power
+	}
+	// Размер результата известен заранее: bitlen(a^n) <= n*bitlen(a)
+	if int64(max(base.Num().BitLen(), base.Denom().BitLen()))*power > maxExactBits {
+		return nil, ErrNotExact
+	}
+
+	p := big.NewInt(power)
+	num := new(big.Int).Exp(base.Num(), p, nil)
+	den := new(big.Int).Exp(base.Denom(), p, nil)
+	if negative {
+		num, den = den, num
+	}
+	return new(big.Rat).SetFrac(num, den), nil
+}
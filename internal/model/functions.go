@@ -0,0 +1,227 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"plugin"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrFunctionCollision = errors.New("function name collision")
+
+var functionNamePattern = regexp.MustCompile(`^[A-Za-z]+$`)
+
+// Имена, которые уже заняты синтаксисом калькулятора
+var reservedNames = map[string]bool{"x": true, "e": true, "pi": true, modKeyword: true, "ans": true}
+
+// Function — функция расширения, вычисляемая на стороне Go.
+type Function struct {
+	Name  string
+	Arity int
+	Doc   string
+	// Файл пакета расширений; пусто для функций, зарегистрированных из кода
+	Pack string
+
+	impl func(...float64) (float64, error)
+}
+
+func (f Function) source() string {
+	if f.Pack == "" {
+		return "the application"
+	}
+	return f.Pack
+}
+
+// RegisterFunction добавляет функцию, доступную в выражениях как name(a, b, ...).
+func (m *Model) RegisterFunction(name string, arity int, impl func(...float64) (float64, error), doc string) error {
+	return m.registerFunction(Function{Name: name, Arity: arity, Doc: doc, impl: impl})
+}
+
+func (m *Model) registerFunction(f Function) error {
+	if !functionNamePattern.MatchString(f.Name) {
+		return fmt.Errorf("invalid function name %q: only latin letters are allowed", f.Name)
+	}
+	if f.Arity < 0 {
+		return fmt.Errorf("invalid arity %d for function %s", f.Arity, f.Name)
+	}
+	if f.impl == nil {
+		return fmt.Errorf("function %s has no implementation", f.Name)
+	}
+	if _, builtin := functionAliases[f.Name]; builtin || reservedNames[f.Name] {
+		return fmt.Errorf("%w: %s from %s is a built-in name", ErrFunctionCollision, f.Name, f.source())
+	}
+
+	m.functionsMu.Lock()
+	defer m.functionsMu.Unlock()
+	if existing, ok := m.functions[f.Name]; ok {
+		return fmt.Errorf("%w: %s from %s is already registered by %s", ErrFunctionCollision, f.Name, f.source(), existing.source())
+	}
+	if m.functions == nil {
+		m.functions = make(map[string]Function)
+	}
+	m.functions[f.Name] = f
+	return nil
+}
+
+// Functions возвращает зарегистрированные функции расширений по алфавиту.
+func (m *Model) Functions() []Function {
+	m.functionsMu.RLock()
+	defer m.functionsMu.RUnlock()
+
+	functions := make([]Function, 0, len(m.functions))
+	for _, f := range m.functions {
+		functions = append(functions, f)
+	}
+	sort.Slice(functions, func(i, j int) bool { return functions[i].Name < functions[j].Name })
+	return functions
+}
+
+func (m *Model) lookupFunction(name string) (Function, bool) {
+	m.functionsMu.RLock()
+	defer m.functionsMu.RUnlock()
+	f, ok := m.functions[name]
+	return f, ok
+}
+
+func (m *Model) hasFunctionCalls(tokens []Token) bool {
+	for i, tok := range tokens {
+		if tok.Kind == TokenIdent && i+1 < len(tokens) && tokens[i+1].Kind == TokenLParen {
+			if _, ok := m.lookupFunction(tok.Text); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// expandFunctions заменяет вызовы функций расширений их значениями: аргументы
+// вычисляет ядро, саму функцию — Go. Остальное выражение остается ядру.
+func (m *Model) expandFunctions(ctx context.Context, eng *engine, tokens []Token, vars map[string]float64) (string, error) {
+	var result strings.Builder
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		f, ok := m.lookupFunction(tok.Text)
+		if tok.Kind != TokenIdent || !ok || i+1 >= len(tokens) || tokens[i+1].Kind != TokenLParen {
+			result.WriteString(tok.Text)
+			continue
+		}
+
+		args, end, err := splitArguments(tokens, i+1)
+		if err != nil {
+			return "", err
+		}
+		if len(args) != f.Arity {
+			return "", fmt.Errorf("%s expects %d arguments, got %d", f.Name, f.Arity, len(args))
+		}
+
+		values := make([]float64, len(args))
+		for j, arg := range args {
+			if len(arg) == 0 {
+				return "", fmt.Errorf("%s: argument %d is empty", f.Name, j+1)
+			}
+			expanded, err := m.expandFunctions(ctx, eng, arg, vars)
+			if err != nil {
+				return "", err
+			}
+			if values[j], err = eng.calculateVars(ctx, expanded, vars); err != nil {
+				return "", fmt.Errorf("%s: argument %d: %w", f.Name, j+1, err)
+			}
+		}
+
+		value, err := f.impl(values...)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", f.Name, err)
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return "", fmt.Errorf("%s: result is not a finite number", f.Name)
+		}
+		result.WriteString("(" + strconv.FormatFloat(value, 'g', -1, 64) + ")")
+		i = end
+	}
+	return result.String(), nil
+}
+
+// splitArguments делит содержимое скобок, начинающихся с open, по запятым верхнего уровня.
+func splitArguments(tokens []Token, open int) ([][]Token, int, error) {
+	var args [][]Token
+	depth, start := 0, open+1
+	for i := open; i < len(tokens); i++ {
+		switch tokens[i].Kind {
+		case TokenLParen:
+			depth++
+		case TokenRParen:
+			depth--
+			if depth == 0 {
+				if i > start || len(args) > 0 {
+					args = append(args, tokens[start:i])
+				}
+				return args, i, nil
+			}
+		case TokenComma:
+			if depth == 1 {
+				args = append(args, tokens[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return nil, 0, errors.New("missing closing bracket")
+}
+
+// LoadExtensions загружает пакеты функций (*.so) из каталога. Пакет — Go-плагин
+// с функцией Register, которой передается регистратор:
+//
+//	func Register(register func(name string, arity int, impl func(...float64) (float64, error), doc string) error) error
+//
+// Ошибки отдельных пакетов и конфликты имен возвращаются списком, остальные
+// пакеты при этом загружаются.
+func (m *Model) LoadExtensions(dir string) []error {
+	if _, err := os.Stat(dir); err != nil {
+		return []error{fmt.Errorf("extensions directory: %w", err)}
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.so"))
+	if err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	for _, path := range paths {
+		pack := filepath.Base(path)
+		plug, err := plugin.Open(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("extension %s: %w", pack, err))
+			continue
+		}
+		sym, err := plug.Lookup("Register")
+		if err != nil {
+			errs = append(errs, fmt.Errorf("extension %s: %w", pack, err))
+			continue
+		}
+		register, ok := sym.(func(func(string, int, func(...float64) (float64, error), string) error) error)
+		if !ok {
+			errs = append(errs, fmt.Errorf("extension %s: unexpected type for Register: %T", pack, sym))
+			continue
+		}
+
+		reported := make(map[error]bool)
+		err = register(func(name string, arity int, impl func(...float64) (float64, error), doc string) error {
+			err := m.registerFunction(Function{Name: name, Arity: arity, Doc: doc, Pack: pack, impl: impl})
+			if err != nil {
+				reported[err] = true
+				errs = append(errs, err)
+			}
+			return err
+		})
+		// Пакет может вернуть ошибку регистратора как есть — она уже в списке
+		if err != nil && !reported[err] {
+			errs = append(errs, fmt.Errorf("extension %s: %w", pack, err))
+		}
+	}
+	return errs
+}
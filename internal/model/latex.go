@@ -0,0 +1,93 @@
+package model
+
+import (
+	"strings"
+)
+
+var latexFunctions = map[string]string{
+	"sin": `\sin`, "cos": `\cos`, "tan": `\tan`,
+	"asin": `\arcsin`, "acos": `\arccos`, "atan": `\arctan`,
+	"ln": `\ln`, "log": `\log`,
+}
+
+// FormatLaTeX переводит выражение калькулятора в LaTeX: 1/2 — \frac{1}{2},
+// sqrt(x) — \sqrt{x}, pi — \pi.
+func FormatLaTeX(expression string, mode PercentMode) (string, error) {
+	tree, err := parseWithMode(expression, mode == PercentCalculator)
+	if err != nil {
+		return "", err
+	}
+	return latexNode(tree), nil
+}
+
+func latexNode(n node) string {
+	switch n := n.(type) {
+	case *numberNode:
+		return latexNumber(n.Text)
+	case *identNode:
+		switch {
+		case n.Name == "pi":
+			return `\pi`
+		case len(n.Name) == 1:
+			return n.Name
+		}
+		return `\mathrm{` + n.Name + `}`
+	case *callNode:
+		args := make([]string, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = latexNode(arg)
+		}
+		if n.Name == "sqrt" && len(args) == 1 {
+			return `\sqrt{` + args[0] + `}`
+		}
+		name, ok := latexFunctions[n.Name]
+		if !ok {
+			name = `\operatorname{` + n.Name + `}`
+		}
+		return name + latexParens(strings.Join(args, ", "))
+	case *percentNode:
+		return latexOperand(n.Operand, 5) + `\%`
+	case *unaryNode:
+		// Дробь в скобках не нуждается: -\frac{1}{2}
+		if b, ok := n.Operand.(*binaryNode); ok && b.Op == "/" {
+			return n.Op + latexNode(b)
+		}
+		return n.Op + latexOperand(n.Operand, precedence(n))
+	case *binaryNode:
+		prec := precedence(n)
+		switch n.Op {
+		case "/":
+			return `\frac{` + latexNode(n.Left) + `}{` + latexNode(n.Right) + `}`
+		case "^":
+			return `{` + latexOperand(n.Left, 5) + `}^{` + latexNode(n.Right) + `}`
+		}
+
+		right := latexNode(n.Right)
+		if _, unary := n.Right.(*unaryNode); unary || precedence(n.Right) < prec || (precedence(n.Right) == prec && n.Op != "+" && n.Op != "*") {
+			right = latexParens(right)
+		}
+		op := map[string]string{"+": " + ", "-": " - ", "*": ` \cdot `, "%": ` \bmod `}[n.Op]
+		return latexOperand(n.Left, prec) + op + right
+	}
+	return ""
+}
+
+func latexOperand(n node, prec int) string {
+	if precedence(n) < prec {
+		return latexParens(latexNode(n))
+	}
+	return latexNode(n)
+}
+
+func latexParens(text string) string {
+	return `\left(` + text + `\right)`
+}
+
+// 1.5e+10 — 1.5 \cdot 10^{10}
+func latexNumber(text string) string {
+	mantissa, exponent, ok := strings.Cut(text, "e")
+	if !ok {
+		return text
+	}
+	return mantissa + ` \cdot 10^{` + strings.TrimPrefix(exponent, "+") + `}`
+}
@@ -0,0 +1,211 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+type Limits struct {
+	MaxDepth      int
+	MaxTokens     int
+	MaxIterations int
+}
+
+var DefaultLimits = Limits{
+	MaxDepth:      32,
+	MaxTokens:     256,
+	MaxIterations: 100000,
+}
+
+var ErrLimitExceeded = errors.New("evaluation limit exceeded")
+
+// ErrEngineBusy — ядро в этом процессе еще считает выражение, которое перестали
+// ждать по дедлайну; новые вызовы не запускаются, пока оно не закончится.
+var ErrEngineBusy = errors.New("calculation engine is still busy with a timed-out expression")
+
+// SetLimits можно вызывать во время вычислений: лимиты читаются атомарно.
+func (m *Model) SetLimits(limits Limits) {
+	m.limits.Store(&limits)
+}
+
+func (m *Model) Limits() Limits {
+	return *m.limits.Load()
+}
+
+func (l Limits) checkTokens(tokens []Token) error {
+	if l.MaxTokens > 0 && len(tokens) > l.MaxTokens {
+		return fmt.Errorf("%w: expression has %d tokens, maximum is %d", ErrLimitExceeded, len(tokens), l.MaxTokens)
+	}
+
+	depth := 0
+	for _, tok := range tokens {
+		switch tok.Kind {
+		case TokenLParen:
+			depth++
+			if l.MaxDepth > 0 && depth > l.MaxDepth {
+				return fmt.Errorf("%w: nesting depth exceeds %d", ErrLimitExceeded, l.MaxDepth)
+			}
+		case TokenRParen:
+			depth--
+		}
+	}
+	return nil
+}
+
+func (l Limits) checkExpression(expression string) error {
+	tokens, err := Tokenize(expression)
+	if err != nil {
+		return err
+	}
+	return l.checkTokens(tokens)
+}
+
+func (l Limits) CheckIterations(n int) error {
+	if l.MaxIterations > 0 && n > l.MaxIterations {
+		return fmt.Errorf("%w: %d iterations requested, maximum is %d", ErrLimitExceeded, n, l.MaxIterations)
+	}
+	return nil
+}
+
+// CalculateContext вычисляет выражение с учетом лимитов модели и дедлайна ctx.
+// vars подставляются вместо одноименных идентификаторов; x ядро получает числом.
+func (m *Model) CalculateContext(ctx context.Context, expression string, vars map[string]float64) (float64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	eng := m.currentEngine()
+	if eng == nil || eng.calculateContext == nil {
+		return 0, fmt.Errorf("plugin not loaded or Calculate function not set")
+	}
+
+	tokens, err := Tokenize(expression)
+	if err != nil {
+		return 0, err
+	}
+	if err := m.Limits().checkTokens(tokens); err != nil {
+		return 0, err
+	}
+	if err := eng.capabilities.checkSupported(tokens); err != nil {
+		return 0, err
+	}
+
+	resolved, err := m.resolve(ctx, eng, tokens, vars)
+	if err != nil {
+		return 0, err
+	}
+	return eng.calculateVars(ctx, resolved, vars)
+}
+
+// resolve подставляет переменные и значения функций расширений — результат
+// понятен ядру без дополнительных знаний.
+func (m *Model) resolve(ctx context.Context, eng *engine, tokens []Token, vars map[string]float64) (string, error) {
+	tokens = substituteVars(tokens, vars)
+	if m.hasFunctionCalls(tokens) {
+		return m.expandFunctions(ctx, eng, tokens, vars)
+	}
+	return joinTokens(tokens), nil
+}
+
+// substituteVars не трогает x: его значение уходит в ядро числом, см. calculateVars.
+func substituteVars(tokens []Token, vars map[string]float64) []Token {
+	result := make([]Token, len(tokens))
+	for i, tok := range tokens {
+		if value, ok := vars[tok.Text]; ok && tok.Kind == TokenIdent && tok.Text != "x" {
+			tok = Token{Kind: TokenNumber, Text: "(" + strconv.FormatFloat(value, 'g', -1, 64) + ")", Pos: tok.Pos}
+		}
+		result[i] = tok
+	}
+	return result
+}
+
+// calculateVars вычисляет подготовленное выражение. Ядро с CalculateBatch
+// получает x числом и подставляет его уже в разобранное выражение (ValueX):
+// текст теряет последний бит у значений вроде 2.0658266191369858e+09.
+// Старым плагинам x по-прежнему передается текстом.
+func (e *engine) calculateVars(ctx context.Context, expression string, vars map[string]float64) (float64, error) {
+	x, ok := vars["x"]
+	switch {
+	case !ok:
+		return e.calculateContext(ctx, &expression, "")
+	case e.calculateBatch != nil:
+		values, errs := e.calculateBatch(ctx, expression, []float64{x})
+		if len(values) != 1 || len(errs) != 1 {
+			return 0, fmt.Errorf("engine returned %d results for one value of x", len(values))
+		}
+		return values[0], errs[0]
+	default:
+		return e.calculateContext(ctx, &expression, "("+strconv.FormatFloat(x, 'g', -1, 64)+")")
+	}
+}
+
+func joinTokens(tokens []Token) string {
+	var result strings.Builder
+	for _, tok := range tokens {
+		result.WriteString(tok.Text)
+	}
+	return result.String()
+}
+
+// abandonedCalls считает вызовы ядра, которые перестали ждать по дедлайну,
+// но которые еще выполняются.
+type abandonedCalls struct {
+	running atomic.Int32
+}
+
+func (a *abandonedCalls) check() error {
+	if a.running.Load() > 0 {
+		return ErrEngineBusy
+	}
+	return nil
+}
+
+func calculateInBackground(calculate func(*string, string) (float64, error), abandoned *abandonedCalls) func(context.Context, *string, string) (float64, error) {
+	return func(ctx context.Context, s *string, x string) (float64, error) {
+		type result struct {
+			value float64
+			err   error
+		}
+
+		expression := *s
+		r, err := inBackground(ctx, abandoned, func() result {
+			value, err := calculate(&expression, x)
+			return result{value, err}
+		})
+		if err != nil {
+			return 0, err
+		}
+		*s = expression
+		return r.value, r.err
+	}
+}
+
+// inBackground выполняет вызов ядра в одном процессе с учетом ctx. Прервать
+// такой вызов нельзя: по дедлайну мы только перестаем его ждать и не пускаем
+// в ядро новые вызовы, пока брошенный не закончится. Полная отмена доступна
+// в изолированном режиме.
+func inBackground[T any](ctx context.Context, abandoned *abandonedCalls, call func() T) (T, error) {
+	var zero T
+	if err := abandoned.check(); err != nil {
+		return zero, err
+	}
+	done := make(chan T, 1)
+	go func() {
+		done <- call()
+	}()
+
+	select {
+	case r := <-done:
+		return r, nil
+	case <-ctx.Done():
+		abandoned.running.Add(1)
+		go func() {
+			<-done
+			abandoned.running.Add(-1)
+		}()
+		return zero, ctx.Err()
+	}
+}
@@ -1,17 +1,40 @@
 package model
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"plugin"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
 )
 
 type Model struct {
-	library              string
+	library      string
+	engine       atomic.Pointer[engine]
+	limits       atomic.Pointer[Limits]
+	batchWorkers int
+
+	functionsMu sync.RWMutex
+	functions   map[string]Function
+
+	reloadMu sync.Mutex
+	watcher  *fsnotify.Watcher
+	closed   bool
+}
+
+type engine struct {
 	creditAnnuity        func(float64, float64, float64) (float64, float64, float64, error)
 	creditDifferentiated func(float64, float64, float64) (float64, float64, float64, float64, error)
 	calculate            func(*string, string) (float64, error)
+	calculateContext     func(context.Context, *string, string) (float64, error)
+	calculateBatch       func(context.Context, string, []float64) ([]float64, []error)
 	pluginObj            *plugin.Plugin
+	worker               *worker
+	version              string
+	capabilities         Capabilities
 }
 
 func NewModel(libraryPath string) (*Model, error) {
@@ -19,42 +42,61 @@ func NewModel(libraryPath string) (*Model, error) {
 		return nil, fmt.Errorf("library path cannot be empty")
 	}
 
-	plug, err := plugin.Open(libraryPath)
+	eng, err := loadPluginEngine(libraryPath)
 	if err != nil {
-		log.Printf("Error opening plugin: %v", err)
 		return nil, err
 	}
 
-	symCreditAnnuity, err := plug.Lookup("CreditAnnuity")
+	m := &Model{library: libraryPath}
+	m.SetLimits(DefaultLimits)
+	m.engine.Store(eng)
+	return m, nil
+}
+
+// NewIsolatedModel загружает плагин в отдельном процессе: падение ядра
+// превращается в ошибку вычисления, а процесс перезапускается.
+func NewIsolatedModel(libraryPath string) (*Model, error) {
+	if libraryPath == "" {
+		return nil, fmt.Errorf("library path cannot be empty")
+	}
+
+	eng, err := startWorkerEngine(libraryPath)
 	if err != nil {
-		log.Printf("Error finding creditAnnuity function: %v", err)
 		return nil, err
 	}
 
-	//log.Printf("Type of symCreditAnnuity: %T", symCreditAnnuity)
+	m := &Model{library: libraryPath}
+	m.SetLimits(DefaultLimits)
+	m.engine.Store(eng)
+	return m, nil
+}
 
-	creditAnnuityFunc, ok := symCreditAnnuity.(func(float64, float64, float64) (float64, float64, float64, error))
-	if !ok {
-		err := fmt.Errorf("unexpected type for creditAnnuity: %T", symCreditAnnuity)
-		log.Println(err)
-		return nil, err
-	}
+func (m *Model) Close() error {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
 
-	symCreditDifferentiated, err := plug.Lookup("CreditDifferentiated")
-	if err != nil {
-		log.Printf("Error finding creditDifferentiated function: %v", err)
-		return nil, err
+	m.closed = true
+	if m.watcher != nil {
+		m.watcher.Close()
+	}
+	if eng := m.currentEngine(); eng != nil && eng.worker != nil {
+		return eng.worker.close()
 	}
+	return nil
+}
 
-	//log.Printf("Type of symCreditDifferentiated: %T", symCreditDifferentiated)
+func (m *Model) currentEngine() *engine {
+	return m.engine.Load()
+}
 
-	creditDifferentiatedFunc, ok := symCreditDifferentiated.(func(float64, float64, float64) (float64, float64, float64, float64, error))
-	if !ok {
-		err := fmt.Errorf("unexpected type for creditDifferentiated: %T", symCreditDifferentiated)
-		log.Println(err)
+func loadPluginEngine(libraryPath string) (*engine, error) {
+	plug, err := plugin.Open(libraryPath)
+	if err != nil {
+		log.Printf("Error opening plugin: %v", err)
 		return nil, err
 	}
 
+	// Обязателен только Calculate; остальные символы расширяют возможности ядра
 	symCalculate, err := plug.Lookup("Calculate")
 	if err != nil {
 		log.Printf("Error finding Calculate function: %v", err)
@@ -63,39 +105,101 @@ func NewModel(libraryPath string) (*Model, error) {
 
 	calculateFunc, ok := symCalculate.(func(*string, string) (float64, error))
 	if !ok {
-		err := fmt.Errorf("failed to cast Calculate function")
+		err := fmt.Errorf("unexpected type for Calculate: %T", symCalculate)
 		log.Println(err)
 		return nil, err
 	}
 
-	return &Model{
-		library:              libraryPath,
-		creditAnnuity:        creditAnnuityFunc,
-		creditDifferentiated: creditDifferentiatedFunc,
-		calculate:            calculateFunc,
-		pluginObj:            plug,
-	}, nil
+	// Все вызовы ядра проверяют, не выполняется ли еще брошенный по дедлайну
+	abandoned := new(abandonedCalls)
+	eng := &engine{
+		calculate: func(s *string, x string) (float64, error) {
+			if err := abandoned.check(); err != nil {
+				return 0, err
+			}
+			return calculateFunc(s, x)
+		},
+		calculateContext: calculateInBackground(calculateFunc, abandoned),
+		pluginObj:        plug,
+	}
+
+	if sym, err := plug.Lookup("CreditAnnuity"); err == nil {
+		if creditAnnuityFunc, ok := sym.(func(float64, float64, float64) (float64, float64, float64, error)); ok {
+			eng.creditAnnuity = func(sum, duration, rate float64) (float64, float64, float64, error) {
+				if err := abandoned.check(); err != nil {
+					return 0, 0, 0, err
+				}
+				return creditAnnuityFunc(sum, duration, rate)
+			}
+		} else {
+			log.Printf("Ignoring CreditAnnuity with unexpected type %T", sym)
+		}
+	}
+
+	if sym, err := plug.Lookup("CreditDifferentiated"); err == nil {
+		if creditDifferentiatedFunc, ok := sym.(func(float64, float64, float64) (float64, float64, float64, float64, error)); ok {
+			eng.creditDifferentiated = func(sum, duration, rate float64) (float64, float64, float64, float64, error) {
+				if err := abandoned.check(); err != nil {
+					return 0, 0, 0, 0, err
+				}
+				return creditDifferentiatedFunc(sum, duration, rate)
+			}
+		} else {
+			log.Printf("Ignoring CreditDifferentiated with unexpected type %T", sym)
+		}
+	}
+
+	if sym, err := plug.Lookup("CalculateBatch"); err == nil {
+		if calculateBatchFunc, ok := sym.(func(string, []float64) ([]float64, []error)); ok {
+			eng.calculateBatch = func(ctx context.Context, expression string, xs []float64) ([]float64, []error) {
+				type result struct {
+					values []float64
+					errs   []error
+				}
+				r, err := inBackground(ctx, abandoned, func() result {
+					values, errs := calculateBatchFunc(expression, xs)
+					return result{values, errs}
+				})
+				if err != nil {
+					errs := make([]error, len(xs))
+					fillErrors(errs, err)
+					return make([]float64, len(xs)), errs
+				}
+				return r.values, r.errs
+			}
+		} else {
+			log.Printf("Ignoring CalculateBatch with unexpected type %T", sym)
+		}
+	}
+
+	discoverCapabilities(plug, eng)
+	return eng, nil
 }
 
 func (m *Model) Calculate(s *string, x string) (float64, error) {
 
-	if m.pluginObj == nil || m.calculate == nil {
+	eng := m.currentEngine()
+	if eng == nil || eng.calculate == nil {
 		err := fmt.Errorf("plugin not loaded or Calculate function not set")
 		log.Println(err)
 		return 0, err
 	}
-	return m.calculate(s, x)
+	if err := m.Limits().checkExpression(*s); err != nil {
+		return 0, err
+	}
+	return eng.calculate(s, x)
 }
 
 func (m *Model) CreditAnnuity(sum_of_credit, duration_of_credit, annual_interest_rate float64) (float64, float64, float64, error) {
 
-	if m.pluginObj == nil || m.creditAnnuity == nil {
+	eng := m.currentEngine()
+	if eng == nil || eng.creditAnnuity == nil {
 		err := fmt.Errorf("plugin not loaded or creditAnnuity function not set")
 		log.Println(err)
 		return 0, 0, 0, err
 	}
 
-	month_pay, over_pay, all_sum_of_pay, err := m.creditAnnuity(sum_of_credit, duration_of_credit, annual_interest_rate)
+	month_pay, over_pay, all_sum_of_pay, err := eng.creditAnnuity(sum_of_credit, duration_of_credit, annual_interest_rate)
 
 	if err != nil {
 		log.Printf("Error in CreditAnnuity: %v", err)
@@ -107,13 +211,14 @@ func (m *Model) CreditAnnuity(sum_of_credit, duration_of_credit, annual_interest
 
 func (m *Model) CreditDifferentiated(sum_of_credit, duration_of_credit, annual_interest_rate float64) (float64, float64, float64, float64, error) {
 
-	if m.pluginObj == nil || m.creditDifferentiated == nil {
+	eng := m.currentEngine()
+	if eng == nil || eng.creditDifferentiated == nil {
 		err := fmt.Errorf("plugin not loaded or creditDifferentiated function not set")
 		log.Println(err)
 		return 0, 0, 0, 0, err
 	}
 
-	month_pay_first, month_pay_last, over_pay, all_sum_of_pay, err := m.creditDifferentiated(sum_of_credit, duration_of_credit, annual_interest_rate)
+	month_pay_first, month_pay_last, over_pay, all_sum_of_pay, err := eng.creditDifferentiated(sum_of_credit, duration_of_credit, annual_interest_rate)
 
 	if err != nil {
 		log.Printf("Error in CreditDifferentiated: %v", err)
@@ -11,6 +11,17 @@ std::string DoubleToString(double value) {
 namespace s21 {
 
 int s21::Model::Calculate(std::string &str, double x) {
+  double result = 0;
+  int flag = Evaluate(str, x, &result);
+  if (flag) {
+    str = DoubleToString(result);
+    Check(str);
+    CheckE(str);
+  }
+  return flag;
+}
+
+int s21::Model::Evaluate(std::string &str, double x, double *result) {
   int flag = 0;
   if (str.length() < 255) {
     Replace(str, "e-", "/10^");
@@ -24,10 +35,7 @@ int s21::Model::Calculate(std::string &str, double x) {
       auto tmp = Parser(str);
       ValueX(x, tmp);
       PolisNotation(tmp);
-      double result_polis_notation = Counter(tmp);
-      str = DoubleToString(result_polis_notation);
-      Check(str);
-      CheckE(str);
+      *result = Counter(tmp);
       flag = 1;
     } catch (const std::runtime_error &e) {
       str = e.what();
@@ -38,6 +46,36 @@ int s21::Model::Calculate(std::string &str, double x) {
   return flag;
 }
 
+int s21::Model::CalculateBatch(std::string str, const double *xs,
+                               double *results, int *statuses, size_t count) {
+  if (str.length() >= 255) return 0;
+  Replace(str, "e-", "/10^");
+  Replace(str, "e+", "*10^");
+  ReplaceDot(str, ".", "0.", ".0");
+  if (!CheckFunctions(str)) return 0;
+
+  // Разбор выполняется один раз, для каждой точки подставляется только x
+  std::list<Lexeme> lexemes;
+  try {
+    lexemes = Parser(str);
+  } catch (const std::runtime_error &e) {
+    return 0;
+  }
+  for (size_t i = 0; i < count; i++) {
+    statuses[i] = 0;
+    try {
+      auto tmp = lexemes;
+      ValueX(xs[i], tmp);
+      PolisNotation(tmp);
+      results[i] = Counter(tmp);
+      statuses[i] = 1;
+    } catch (const std::runtime_error &e) {
+      results[i] = NAN;
+    }
+  }
+  return 1;
+}
+
 std::list<s21::Model::Lexeme> s21::Model::Parser(const std::string &str) {
   std::list<Lexeme> List;
   char first = str[0];
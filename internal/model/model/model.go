@@ -9,8 +9,8 @@ package main
 import "C"
 import (
 	"errors"
+	"math"
 	"regexp"
-	"strconv"
 	"strings"
 	"unsafe"
 )
@@ -25,30 +25,24 @@ func Calculate(expression *string, x string) (float64, error) {
 	cStr := C.CString(*expression)
 	defer C.free(unsafe.Pointer(cStr))
 
-	// Создаём буфер для результата
-	bufferSize := C.size_t(256)
-	resultBuffer := (*C.char)(C.malloc(bufferSize))
-	defer C.free(unsafe.Pointer(resultBuffer))
-
-	// Вызываем C-функцию для вычисления
-	status := C.calculate(cStr, resultBuffer, bufferSize)
-	if status == 1 {
-		// Преобразуем результат из C-строки в Go-строку
-		result := C.GoString(resultBuffer)
-
-		// Преобразуем строку в число с плавающей точкой
-		value, err := strconv.ParseFloat(result, 64)
-		if err != nil {
-			return 0.0, err
-		}
-
-		return value, nil
+	// Ядро возвращает double напрямую, без форматирования в строку
+	// NaN (например, sqrt(-16)) считается ошибкой вычисления
+	var result C.double
+	if C.calculate(cStr, &result) == 1 && !math.IsNaN(float64(result)) {
+		return float64(result), nil
 	}
 
 	return 0.0, errors.New("calculation error")
 }
 
 func parser(expression *string, x string) error {
+	normalize(expression)
+	*expression = strings.ReplaceAll(*expression, "x", x)
+	replaceExponent(expression)
+	return validate(*expression, false)
+}
+
+func normalize(expression *string) {
 	replacements := map[string]string{
 		"sqrt": "q",
 		"acos": "C",
@@ -66,29 +60,88 @@ func parser(expression *string, x string) error {
 	}
 
 	*expression = strings.ReplaceAll(*expression, " ", "")
-	*expression = strings.ReplaceAll(*expression, "x", x)
+}
+
+var (
+	invalidOperators  = regexp.MustCompile(`[+\-*/^%]{2,}`)
+	invalidExpression = regexp.MustCompile(`\d+[a-zA-Z]+|\*\*|[^\d\+\-\*/^%.()a-zA-Z0-9]`)
+	validExpression   = regexp.MustCompile(`^[+\-*/%^cstCSTqLle.()0123456789]+$`)
+	validExpressionX  = regexp.MustCompile(`^[+\-*/%^cstCSTqLlex.()0123456789]+$`)
+)
 
+func replaceExponent(expression *string) {
 	*expression = strings.ReplaceAll(*expression, "e-", "/10^")
 	*expression = strings.ReplaceAll(*expression, "e+", "*10^")
+}
 
-	invalidOperators := regexp.MustCompile(`[+\-*/^%]{2,}`)
-	if invalidOperators.MatchString(*expression) {
+func validate(expression string, allowX bool) error {
+	if invalidOperators.MatchString(expression) {
 		return errors.New("invalid expression: contains consecutive operators")
 	}
 
-	invalidExpression := regexp.MustCompile(`\d+[a-zA-Z]+|\*\*|[^\d\+\-\*/^%.()a-zA-Z0-9]`)
-	if invalidExpression.MatchString(*expression) {
+	if invalidExpression.MatchString(expression) {
 		return errors.New("invalid expression: contains invalid characters or operators")
 	}
 
-	match, _ := regexp.MatchString(`^[+\-*/%^cstCSTqLle.()0123456789]+$`, *expression)
-	if match {
+	if validExpression.MatchString(expression) || (allowX && validExpressionX.MatchString(expression)) {
 		return nil
 	}
 
 	return errors.New("invalid expression")
 }
 
+// CalculateBatch вычисляет одно выражение для набора x за один вызов ядра:
+// выражение разбирается один раз, x подставляется уже в готовую нотацию.
+func CalculateBatch(expression string, xs []float64) ([]float64, []error) {
+	results := make([]float64, len(xs))
+	errs := make([]error, len(xs))
+	if len(xs) == 0 {
+		return results, errs
+	}
+
+	normalize(&expression)
+	replaceExponent(&expression)
+	if err := validate(expression, true); err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return results, errs
+	}
+
+	cStr := C.CString(expression)
+	defer C.free(unsafe.Pointer(cStr))
+
+	cXs := (*C.double)(C.malloc(C.size_t(len(xs)) * C.size_t(unsafe.Sizeof(C.double(0)))))
+	defer C.free(unsafe.Pointer(cXs))
+	cResults := (*C.double)(C.malloc(C.size_t(len(xs)) * C.size_t(unsafe.Sizeof(C.double(0)))))
+	defer C.free(unsafe.Pointer(cResults))
+	cStatuses := (*C.int)(C.malloc(C.size_t(len(xs)) * C.size_t(unsafe.Sizeof(C.int(0)))))
+	defer C.free(unsafe.Pointer(cStatuses))
+
+	xsView := unsafe.Slice(cXs, len(xs))
+	for i, x := range xs {
+		xsView[i] = C.double(x)
+	}
+
+	if C.calculate_batch(cStr, cXs, cResults, cStatuses, C.size_t(len(xs))) != 1 {
+		for i := range errs {
+			errs[i] = errors.New("calculation error")
+		}
+		return results, errs
+	}
+
+	resultsView := unsafe.Slice(cResults, len(xs))
+	statusesView := unsafe.Slice(cStatuses, len(xs))
+	for i := range xs {
+		if statusesView[i] != 1 || math.IsNaN(float64(resultsView[i])) {
+			errs[i] = errors.New("calculation error")
+			continue
+		}
+		results[i] = float64(resultsView[i])
+	}
+	return results, errs
+}
+
 func CreditAnnuity(creditSum, creditDuration, annualInterestRate float64) (float64, float64, float64, error) {
 	if creditSum <= 0 || creditDuration <= 0 || annualInterestRate <= 0 {
 		return 0.0, 0.0, 0.0, errors.New("invalid input values for CreditAnnuity")
@@ -123,4 +176,17 @@ func CreditDifferentiated(creditSum, creditDuration, annualInterestRate float64)
 	return float64(monthPayFirst), float64(monthPayLast), float64(overPay), float64(totalPay), nil
 }
 
+// Version и Capabilities необязательны: без них модель считает ядро классическим
+func Version() string {
+	return "3.0.0"
+}
+
+func Capabilities() map[string][]string {
+	return map[string][]string{
+		"functions": {"sqrt", "ln", "log", "sin", "cos", "tan", "asin", "acos", "atan"},
+		"operators": {"+", "-", "*", "/", "^", "%"},
+		"modes":     {"credit", "batch"},
+	}
+}
+
 func main() {}
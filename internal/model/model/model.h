@@ -26,6 +26,9 @@ class Model {
 
  public:
   int Calculate(std::string &str, double x);
+  int Evaluate(std::string &str, double x, double *result);
+  int CalculateBatch(std::string str, const double *xs, double *results,
+                     int *statuses, size_t count);
   std::list<Lexeme> Parser(const std::string &str);
   void PolisNotation(std::list<Lexeme> &List);
   int GetOperators(const char *str);
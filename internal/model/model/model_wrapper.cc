@@ -5,22 +5,16 @@
 
 #include "model.h"
 
-int calculate(const char *expression, char *resultBuffer, size_t bufferSize) {
+int calculate(const char *expression, double *result) {
   s21::Model model;
   std::string expr(expression);
+  return model.Evaluate(expr, 0.0, result);
+}
 
-  if (model.Calculate(expr, 0.0)) {
-    try {
-      std::string formatted = expr;
-      strncpy(resultBuffer, formatted.c_str(), bufferSize - 1);
-      resultBuffer[bufferSize - 1] = '\0';
-    } catch (const std::exception &e) {
-      std::cerr << "Conversion error: " << e.what() << std::endl;
-      return 0;
-    }
-    return 1;
-  }
-  return 0;
+int calculate_batch(const char *expression, const double *xs, double *results,
+                    int *statuses, size_t count) {
+  s21::Model model;
+  return model.CalculateBatch(expression, xs, results, statuses, count);
 }
 
 void creditAnnuity(double sum_of_credit, double duration_of_credit,
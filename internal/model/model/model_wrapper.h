@@ -7,7 +7,10 @@
 extern "C" {
 #endif
 
-int calculate(const char *expression, char *resultBuffer, size_t bufferSize);
+int calculate(const char *expression, double *result);
+
+int calculate_batch(const char *expression, const double *xs, double *results,
+                    int *statuses, size_t count);
 
 void creditAnnuity(double sum_of_credit, double duration_of_credit,
                    double annual_interest_rate, double *month_pay,
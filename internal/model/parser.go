@@ -0,0 +1,268 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+type node interface{}
+
+type numberNode struct {
+	Text string
+}
+
+type identNode struct {
+	Name string
+}
+
+type unaryNode struct {
+	Op      string
+	Operand node
+}
+
+type binaryNode struct {
+	Op          string
+	Left, Right node
+}
+
+type callNode struct {
+	Name string
+	Args []node
+}
+
+type percentNode struct {
+	Operand node
+}
+
+const modKeyword = "mod"
+
+var functionAliases = map[string]string{
+	"sqrt": "sqrt", "q": "sqrt",
+	"acos": "acos", "C": "acos",
+	"asin": "asin", "S": "asin",
+	"atan": "atan", "T": "atan",
+	"cos": "cos", "c": "cos",
+	"sin": "sin", "s": "sin",
+	"tan": "tan", "t": "tan",
+	"log": "log", "L": "log",
+	"ln": "ln", "l": "ln",
+}
+
+// HasNameAfterParen сообщает об имени сразу после закрывающей скобки: (2)x
+// или (1)sin(x) — умножение в калькуляторе явное. mod — оператор, а не имя.
+func HasNameAfterParen(tokens []Token) bool {
+	for i := 1; i < len(tokens); i++ {
+		if tokens[i-1].Kind == TokenRParen && tokens[i].Kind == TokenIdent && tokens[i].Text != modKeyword {
+			return true
+		}
+	}
+	return false
+}
+
+func parse(expression string) (node, error) {
+	return parseWithMode(expression, false)
+}
+
+// percentPostfix: % — постфиксный процент, остаток от деления — только mod
+func parseWithMode(expression string, percentPostfix bool) (node, error) {
+	tokens, err := Tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("expression is empty")
+	}
+
+	p := &parser{tokens: tokens, percentPostfix: percentPostfix}
+	n, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.tokens[p.pos].Text, p.tokens[p.pos].Pos)
+	}
+	return n, nil
+}
+
+type parser struct {
+	tokens         []Token
+	pos            int
+	percentPostfix bool
+}
+
+func (p *parser) peek() *Token {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *parser) peekOperator(ops ...string) string {
+	tok := p.peek()
+	if tok == nil || tok.Kind != TokenOperator {
+		return ""
+	}
+	for _, op := range ops {
+		if tok.Text == op {
+			return op
+		}
+	}
+	return ""
+}
+
+func (p *parser) parseExpression() (node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peekOperator("+", "-"); op != ""; op = p.peekOperator("+", "-") {
+		p.pos++
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{Op: op, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) peekTermOperator() string {
+	if tok := p.peek(); tok != nil && tok.Kind == TokenIdent && tok.Text == modKeyword {
+		return "%"
+	}
+	if p.percentPostfix {
+		return p.peekOperator("*", "/")
+	}
+	return p.peekOperator("*", "/", "%")
+}
+
+func (p *parser) parseTerm() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peekTermOperator(); op != ""; op = p.peekTermOperator() {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{Op: op, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if op := p.peekOperator("+", "-"); op != "" {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{Op: op, Operand: operand}, nil
+	}
+	return p.parsePower()
+}
+
+func (p *parser) parsePower() (node, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.percentPostfix && p.peekOperator("%") != "" {
+		p.pos++
+		base = &percentNode{Operand: base}
+		if next := p.peek(); next != nil && next.Kind != TokenOperator && next.Kind != TokenRParen && next.Text != modKeyword {
+			return nil, fmt.Errorf("missing operator at position %d", next.Pos)
+		}
+	}
+	if p.peekOperator("^") != "" {
+		p.pos++
+		// Степень правоассоциативна: 3^3^2 = 3^(3^2)
+		exponent, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNode{Op: "^", Left: base, Right: exponent}, nil
+	}
+	return base, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.peek()
+	if tok == nil {
+		return nil, errors.New("unexpected end of expression")
+	}
+
+	switch tok.Kind {
+	case TokenNumber:
+		p.pos++
+		if next := p.peek(); next != nil && next.Text != modKeyword && (next.Kind == TokenIdent || next.Kind == TokenLParen || next.Kind == TokenNumber) {
+			return nil, fmt.Errorf("missing operator at position %d", next.Pos)
+		}
+		return &numberNode{Text: tok.Text}, nil
+	case TokenIdent:
+		if tok.Text == modKeyword {
+			break
+		}
+		p.pos++
+		if next := p.peek(); next != nil && next.Kind == TokenLParen {
+			// Имена, которых нет среди встроенных, — функции расширений; их проверяет модель
+			name, ok := functionAliases[tok.Text]
+			if !ok {
+				name = tok.Text
+			}
+			args, err := p.parseArguments()
+			if err != nil {
+				return nil, err
+			}
+			return &callNode{Name: name, Args: args}, nil
+		}
+		return &identNode{Name: tok.Text}, nil
+	case TokenLParen:
+		return p.parseParenthesized()
+	}
+
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.Text, tok.Pos)
+}
+
+func (p *parser) parseArguments() ([]node, error) {
+	p.pos++
+	var args []node
+	if tok := p.peek(); tok != nil && tok.Kind == TokenRParen {
+		p.pos++
+		return args, nil
+	}
+	for {
+		arg, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+
+		tok := p.peek()
+		if tok == nil {
+			return nil, errors.New("missing closing bracket")
+		}
+		p.pos++
+		if tok.Kind == TokenRParen {
+			return args, nil
+		}
+		if tok.Kind != TokenComma {
+			return nil, fmt.Errorf("unexpected %q at position %d", tok.Text, tok.Pos)
+		}
+	}
+}
+
+func (p *parser) parseParenthesized() (node, error) {
+	p.pos++
+	n, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok == nil || tok.Kind != TokenRParen {
+		return nil, errors.New("missing closing bracket")
+	}
+	p.pos++
+	return n, nil
+}
@@ -0,0 +1,116 @@
+package model
+
+import "strings"
+
+type PercentMode int
+
+const (
+	// 200 + 10% = 220, 50 * 10% = 5, остаток от деления — mod
+	PercentCalculator PercentMode = iota
+	// Старое поведение: % — остаток от деления (fmod)
+	PercentModulo
+)
+
+func ParsePercentMode(value string) PercentMode {
+	if strings.EqualFold(strings.TrimSpace(value), "modulo") {
+		return PercentModulo
+	}
+	return PercentCalculator
+}
+
+// TranslatePercent приводит выражение к синтаксису ядра, где % — остаток от деления.
+func TranslatePercent(expression string, mode PercentMode) (string, error) {
+	tokens, err := Tokenize(expression)
+	if err != nil {
+		return "", err
+	}
+
+	hasPercent, hasMod := false, false
+	for _, tok := range tokens {
+		hasPercent = hasPercent || (tok.Kind == TokenOperator && tok.Text == "%")
+		hasMod = hasMod || (tok.Kind == TokenIdent && tok.Text == modKeyword)
+	}
+	if !hasMod && (!hasPercent || mode == PercentModulo) {
+		return expression, nil
+	}
+
+	tree, err := parseWithMode(expression, mode == PercentCalculator)
+	if err != nil {
+		return "", err
+	}
+	return formatNode(rewritePercent(tree)), nil
+}
+
+func rewritePercent(n node) node {
+	switch n := n.(type) {
+	case *unaryNode:
+		return &unaryNode{Op: n.Op, Operand: rewritePercent(n.Operand)}
+	case *callNode:
+		args := make([]node, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = rewritePercent(arg)
+		}
+		return &callNode{Name: n.Name, Args: args}
+	case *percentNode:
+		return &binaryNode{Op: "/", Left: rewritePercent(n.Operand), Right: &numberNode{Text: "100"}}
+	case *binaryNode:
+		left := rewritePercent(n.Left)
+		if percent, ok := n.Right.(*percentNode); ok && (n.Op == "+" || n.Op == "-") {
+			// a ± b% = a ± a*b/100
+			share := &binaryNode{Op: "/", Left: rewritePercent(percent.Operand), Right: &numberNode{Text: "100"}}
+			return &binaryNode{Op: n.Op, Left: left, Right: &binaryNode{Op: "*", Left: left, Right: share}}
+		}
+		return &binaryNode{Op: n.Op, Left: left, Right: rewritePercent(n.Right)}
+	}
+	return n
+}
+
+func precedence(n node) int {
+	switch n := n.(type) {
+	case *binaryNode:
+		switch n.Op {
+		case "+", "-":
+			return 1
+		case "*", "/", "%":
+			return 2
+		case "^":
+			return 4
+		}
+	case *unaryNode:
+		return 3
+	}
+	return 5
+}
+
+func formatNode(n node) string {
+	switch n := n.(type) {
+	case *numberNode:
+		return n.Text
+	case *identNode:
+		return n.Name
+	case *callNode:
+		args := make([]string, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = formatNode(arg)
+		}
+		return n.Name + "(" + strings.Join(args, ",") + ")"
+	case *unaryNode:
+		operand := formatNode(n.Operand)
+		if precedence(n.Operand) < precedence(n) {
+			operand = "(" + operand + ")"
+		}
+		return n.Op + operand
+	case *binaryNode:
+		left, right := formatNode(n.Left), formatNode(n.Right)
+		prec := precedence(n)
+		if precedence(n.Left) < prec || (n.Op == "^" && precedence(n.Left) < 5) {
+			left = "(" + left + ")"
+		}
+		// Ядро не принимает два оператора подряд, поэтому унарный минус справа всегда в скобках
+		if _, unary := n.Right.(*unaryNode); unary || precedence(n.Right) < prec || (precedence(n.Right) == prec && n.Op != "^") {
+			right = "(" + right + ")"
+		}
+		return left + n.Op + right
+	}
+	return ""
+}
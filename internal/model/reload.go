@@ -0,0 +1,127 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Пересборка model.so пишет файл в несколько приемов — ждем, пока запись утихнет
+const reloadDelay = 300 * time.Millisecond
+
+var ErrReloadUnsupported = errors.New("hot reload requires the isolated engine: remove MODEL_ISOLATION=inprocess")
+
+// Watch следит за файлом библиотеки и перезагружает ядро после его пересборки.
+// onReload получает результат каждой попытки: nil или причину, по которой
+// осталось прежнее ядро.
+func (m *Model) Watch(onReload func(error)) error {
+	if eng := m.currentEngine(); eng == nil || eng.worker == nil {
+		// Go-плагин нельзя выгрузить из процесса, новое ядро грузится только в новом воркере
+		return ErrReloadUnsupported
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// Следим за каталогом: сборка может заменить файл новым, а не переписать его
+	if err := watcher.Add(filepath.Dir(m.library)); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+	if m.closed {
+		watcher.Close()
+		return fmt.Errorf("model is closed")
+	}
+	if m.watcher != nil {
+		m.watcher.Close()
+	}
+	m.watcher = watcher
+
+	go m.watchLoop(watcher, onReload)
+	return nil
+}
+
+func (m *Model) watchLoop(watcher *fsnotify.Watcher, onReload func(error)) {
+	library := filepath.Clean(m.library)
+	var timer *time.Timer
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			}
+			if filepath.Clean(event.Name) != library || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(reloadDelay, func() {
+				err := m.Reload()
+				if onReload != nil {
+					onReload(err)
+				}
+			})
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Model watcher error: %v", err)
+		}
+	}
+}
+
+// Reload запускает новый воркер с текущим файлом библиотеки и, если он
+// проходит самопроверку, атомарно подменяет им работающее ядро.
+func (m *Model) Reload() error {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	if m.closed {
+		return fmt.Errorf("model is closed")
+	}
+	old := m.currentEngine()
+	if old == nil || old.worker == nil {
+		return ErrReloadUnsupported
+	}
+
+	eng, err := startWorkerEngine(m.library)
+	if err != nil {
+		log.Printf("Model reload failed, keeping the previous engine: %v", err)
+		return fmt.Errorf("failed to load the new engine: %w", err)
+	}
+	if err := selfTest(eng); err != nil {
+		eng.worker.close()
+		log.Printf("Model reload failed, keeping the previous engine: %v", err)
+		return err
+	}
+
+	m.engine.Store(eng)
+	old.worker.close()
+	log.Printf("Model engine reloaded from %s", m.library)
+	return nil
+}
+
+func selfTest(eng *engine) error {
+	expression := "2+3*(4-1)^2/3"
+	value, err := eng.calculate(&expression, "")
+	if err != nil {
+		return fmt.Errorf("self-test failed: %w", err)
+	}
+	if value != 11 {
+		return fmt.Errorf("self-test failed: 2+3*(4-1)^2/3 = %v, expected 11", value)
+	}
+	return nil
+}
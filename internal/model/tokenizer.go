@@ -0,0 +1,78 @@
+package model
+
+import (
+	"fmt"
+	"unicode"
+)
+
+type TokenKind int
+
+const (
+	TokenNumber TokenKind = iota
+	TokenIdent
+	TokenOperator
+	TokenLParen
+	TokenRParen
+	TokenComma
+)
+
+type Token struct {
+	Kind TokenKind
+	Text string
+	Pos  int
+}
+
+func Tokenize(expression string) ([]Token, error) {
+	var tokens []Token
+	runes := []rune(expression)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			// Экспоненциальная запись вида 1e+10 / 1e-10
+			if i+2 < len(runes) && runes[i] == 'e' && (runes[i+1] == '+' || runes[i+1] == '-') && unicode.IsDigit(runes[i+2]) {
+				i += 2
+				for i < len(runes) && unicode.IsDigit(runes[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, Token{Kind: TokenNumber, Text: string(runes[start:i]), Pos: start})
+		case unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && unicode.IsLetter(runes[i]) {
+				i++
+			}
+			// ans1, ans2, ... — ссылки на предыдущие результаты
+			if string(runes[start:i]) == "ans" {
+				for i < len(runes) && unicode.IsDigit(runes[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, Token{Kind: TokenIdent, Text: string(runes[start:i]), Pos: start})
+		case r == '+' || r == '-' || r == '*' || r == '/' || r == '^' || r == '%':
+			tokens = append(tokens, Token{Kind: TokenOperator, Text: string(r), Pos: i})
+			i++
+		case r == '(':
+			tokens = append(tokens, Token{Kind: TokenLParen, Text: "(", Pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, Token{Kind: TokenRParen, Text: ")", Pos: i})
+			i++
+		case r == ',':
+			// Разделитель аргументов функций расширений
+			tokens = append(tokens, Token{Kind: TokenComma, Text: ",", Pos: i})
+			i++
+		default:
+			return nil, fmt.Errorf("invalid character %q at position %d", r, i)
+		}
+	}
+
+	return tokens, nil
+}
@@ -0,0 +1,367 @@
+package model
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+const workerEnv = "SMARTCALC_MODEL_WORKER"
+
+var ErrEngineCrashed = errors.New("calculation engine crashed")
+
+// Ядро закрыто или заменено при перезагрузке, пока запрос ждал своей очереди
+var errWorkerClosed = errors.New("calculation engine was closed, please retry")
+
+type workerRequest struct {
+	Op         string
+	Expression string
+	X          string
+	Args       []float64
+}
+
+type workerResponse struct {
+	Values     []float64
+	Errors     []string
+	Expression string
+	Error      string
+	// Заполняются только в приветствии
+	Version      string
+	Capabilities Capabilities
+}
+
+// ServeWorkerIfRequested вызывается в самом начале main. Если процесс запущен
+// как worker вычислительного ядра, функция обслуживает запросы до закрытия
+// stdin и возвращает true — после этого процесс должен завершиться.
+func ServeWorkerIfRequested() bool {
+	libraryPath := os.Getenv(workerEnv)
+	if libraryPath == "" {
+		return false
+	}
+
+	// Ответы идут через отдельный дескриптор, чтобы вывод ядра в stdout не ломал протокол
+	out := os.NewFile(3, "model-worker-responses")
+	if err := serveWorker(libraryPath, os.Stdin, out); err != nil {
+		log.Printf("Model worker stopped: %v", err)
+	}
+	return true
+}
+
+func serveWorker(libraryPath string, in io.Reader, out io.Writer) error {
+	decoder := gob.NewDecoder(in)
+	encoder := gob.NewEncoder(out)
+
+	eng, err := loadPluginEngine(libraryPath)
+	if err != nil {
+		return encoder.Encode(workerResponse{Error: err.Error()})
+	}
+	hello := workerResponse{Version: eng.version, Capabilities: eng.capabilities}
+	if err := encoder.Encode(hello); err != nil {
+		return err
+	}
+
+	for {
+		var req workerRequest
+		if err := decoder.Decode(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if err := encoder.Encode(eng.handle(req)); err != nil {
+			return err
+		}
+	}
+}
+
+func (e *engine) handle(req workerRequest) workerResponse {
+	var values []float64
+	var err error
+
+	switch req.Op {
+	case "calculate":
+		var value float64
+		value, err = e.calculate(&req.Expression, req.X)
+		values = []float64{value}
+	case "calculateBatch":
+		if e.calculateBatch == nil {
+			return workerResponse{Error: "plugin does not provide CalculateBatch"}
+		}
+		values, errs := e.calculateBatch(context.Background(), req.Expression, req.Args)
+		return workerResponse{Values: values, Errors: errorStrings(errs)}
+	case "creditAnnuity", "creditDifferentiated":
+		if len(req.Args) != 3 {
+			return workerResponse{Error: fmt.Sprintf("%s expects 3 arguments", req.Op)}
+		}
+		if e.creditAnnuity == nil || e.creditDifferentiated == nil {
+			return workerResponse{Error: "plugin does not provide credit calculations"}
+		}
+		if req.Op == "creditAnnuity" {
+			var monthPay, overPay, totalPay float64
+			monthPay, overPay, totalPay, err = e.creditAnnuity(req.Args[0], req.Args[1], req.Args[2])
+			values = []float64{monthPay, overPay, totalPay}
+		} else {
+			var first, last, overPay, totalPay float64
+			first, last, overPay, totalPay, err = e.creditDifferentiated(req.Args[0], req.Args[1], req.Args[2])
+			values = []float64{first, last, overPay, totalPay}
+		}
+	default:
+		return workerResponse{Error: fmt.Sprintf("unknown worker operation %q", req.Op)}
+	}
+
+	resp := workerResponse{Values: values, Expression: req.Expression}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	return resp
+}
+
+type worker struct {
+	libraryPath string
+
+	mu      sync.Mutex
+	closed  bool
+	hello   workerResponse
+	cmd     *exec.Cmd
+	exited  chan struct{}
+	stdin   io.WriteCloser
+	encoder *gob.Encoder
+	decoder *gob.Decoder
+}
+
+func startWorkerEngine(libraryPath string) (*engine, error) {
+	snapshot, err := snapshotLibrary(libraryPath)
+	if err != nil {
+		return nil, err
+	}
+	w := &worker{libraryPath: snapshot}
+	if err := w.start(); err != nil {
+		os.Remove(snapshot)
+		return nil, err
+	}
+
+	calculateBatch := func(ctx context.Context, expression string, xs []float64) ([]float64, []error) {
+		resp, err := w.callContext(ctx, workerRequest{Op: "calculateBatch", Expression: expression, Args: xs})
+		if err == nil {
+			err = resp.err()
+		}
+		if err != nil {
+			errs := make([]error, len(xs))
+			fillErrors(errs, err)
+			return make([]float64, len(xs)), errs
+		}
+		return resp.Values, stringErrors(resp.Errors, len(xs))
+	}
+
+	calculateContext := func(ctx context.Context, s *string, x string) (float64, error) {
+		resp, err := w.callContext(ctx, workerRequest{Op: "calculate", Expression: *s, X: x})
+		if err != nil {
+			return 0, err
+		}
+		*s = resp.Expression
+		return resp.value(0), resp.err()
+	}
+
+	eng := &engine{
+		calculate: func(s *string, x string) (float64, error) {
+			return calculateContext(context.Background(), s, x)
+		},
+		calculateContext: calculateContext,
+		worker:           w,
+		version:          w.hello.Version,
+		capabilities:     w.hello.Capabilities,
+	}
+	if eng.capabilities.HasMode(ModeBatch) {
+		eng.calculateBatch = calculateBatch
+	}
+	if eng.capabilities.HasMode(ModeCredit) {
+		eng.creditAnnuity = func(sum, duration, rate float64) (float64, float64, float64, error) {
+			resp, err := w.call(workerRequest{Op: "creditAnnuity", Args: []float64{sum, duration, rate}})
+			if err != nil {
+				return 0, 0, 0, err
+			}
+			return resp.value(0), resp.value(1), resp.value(2), resp.err()
+		}
+		eng.creditDifferentiated = func(sum, duration, rate float64) (float64, float64, float64, float64, error) {
+			resp, err := w.call(workerRequest{Op: "creditDifferentiated", Args: []float64{sum, duration, rate}})
+			if err != nil {
+				return 0, 0, 0, 0, err
+			}
+			return resp.value(0), resp.value(1), resp.value(2), resp.value(3), resp.err()
+		}
+	}
+	return eng, nil
+}
+
+// snapshotLibrary копирует библиотеку во временный файл, из которого ее грузит
+// воркер: пересборка оригинала на месте не трогает отображенный в память файл
+// работающего ядра, а перезапуск после падения берет ту же проверенную версию.
+func snapshotLibrary(libraryPath string) (string, error) {
+	src, err := os.Open(libraryPath)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp("", "smartcalc-model-*.so")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return "", err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
+	return dst.Name(), nil
+}
+
+func (r workerResponse) value(i int) float64 {
+	if i < len(r.Values) {
+		return r.Values[i]
+	}
+	return 0
+}
+
+func (r workerResponse) err() error {
+	if r.Error != "" {
+		return errors.New(r.Error)
+	}
+	return nil
+}
+
+func (w *worker) start() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("cannot locate executable for model worker: %w", err)
+	}
+
+	responses, responsesWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer responsesWriter.Close()
+
+	cmd := exec.Command(executable)
+	cmd.Env = append(os.Environ(), workerEnv+"="+w.libraryPath)
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = []*os.File{responsesWriter}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		responses.Close()
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		responses.Close()
+		return fmt.Errorf("failed to start model worker: %w", err)
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		responses.Close()
+		close(exited)
+	}()
+
+	w.cmd = cmd
+	w.exited = exited
+	w.stdin = stdin
+	w.encoder = gob.NewEncoder(stdin)
+	w.decoder = gob.NewDecoder(responses)
+
+	var hello workerResponse
+	if err := w.decoder.Decode(&hello); err != nil {
+		w.stop()
+		return fmt.Errorf("model worker failed to start: %w", err)
+	}
+	if hello.Error != "" {
+		w.stop()
+		return errors.New(hello.Error)
+	}
+	w.hello = hello
+	return nil
+}
+
+func (w *worker) call(req workerRequest) (workerResponse, error) {
+	return w.callContext(context.Background(), req)
+}
+
+func (w *worker) callContext(ctx context.Context, req workerRequest) (workerResponse, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return workerResponse{}, errWorkerClosed
+	}
+	// Перезапуск после падения происходит при следующем запросе
+	if w.cmd == nil {
+		if err := w.start(); err != nil {
+			return workerResponse{}, err
+		}
+	}
+
+	type result struct {
+		resp workerResponse
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var resp workerResponse
+		err := w.encoder.Encode(req)
+		if err == nil {
+			err = w.decoder.Decode(&resp)
+		}
+		done <- result{resp, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return workerResponse{}, w.crashed(r.err)
+		}
+		return r.resp, nil
+	case <-ctx.Done():
+		// Зависшее или слишком долгое вычисление прерывается вместе с процессом
+		w.stop()
+		<-done
+		return workerResponse{}, ctx.Err()
+	}
+}
+
+func (w *worker) crashed(err error) error {
+	cmd := w.cmd
+	w.stop()
+	if cmd.ProcessState != nil {
+		err = errors.New(cmd.ProcessState.String())
+	}
+	log.Printf("Model worker crashed: %v", err)
+	return fmt.Errorf("%w: %v", ErrEngineCrashed, err)
+}
+
+func (w *worker) stop() {
+	if w.cmd == nil {
+		return
+	}
+	w.stdin.Close()
+	_ = w.cmd.Process.Kill()
+	<-w.exited
+	w.cmd = nil
+}
+
+func (w *worker) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	w.stop()
+	os.Remove(w.libraryPath)
+	return nil
+}
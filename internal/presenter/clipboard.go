@@ -0,0 +1,177 @@
+package presenter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/helpers"
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/model"
+)
+
+var pasteReplacer = strings.NewReplacer(
+	"×", "*", "·", "*", "⋅", "*", "∗", "*",
+	"÷", "/", "∕", "/",
+	"−", "-", "–", "-", "—", "-",
+	"π", "pi",
+	"（", "(", "）", ")",
+)
+
+// SanitizeInput приводит вставленный текст к синтаксису калькулятора:
+// × ÷ − и √ — к операторам и sqrt, десятичные запятые — к точкам,
+// пробелы удаляются. Результат проверяется токенизатором модели.
+func (p *Presenter) SanitizeInput(text string) (string, error) {
+	text = pasteReplacer.Replace(strings.Join(strings.Fields(text), ""))
+	text = replaceRootSign(text)
+	text, err := p.replaceDecimalCommas(text)
+	if err != nil {
+		return "", err
+	}
+
+	if text == "" {
+		return "", errors.New("nothing to paste")
+	}
+	if len(text) >= maxDisplayLength {
+		return "", fmt.Errorf("pasted expression is longer than %d characters", maxDisplayLength-1)
+	}
+	tokens, err := model.Tokenize(text)
+	if err != nil {
+		return "", err
+	}
+	for _, tok := range tokens {
+		if tok.Kind == model.TokenIdent && strings.IndexFunc(tok.Text, func(r rune) bool { return r > unicode.MaxASCII }) >= 0 {
+			return "", fmt.Errorf("invalid name %q at position %d", tok.Text, tok.Pos)
+		}
+	}
+	return text, nil
+}
+
+// √2 — sqrt(2), √(1+x) — sqrt(1+x)
+func replaceRootSign(text string) string {
+	var result strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '√' {
+			result.WriteRune(runes[i])
+			continue
+		}
+		result.WriteString("sqrt")
+		if i+1 < len(runes) && runes[i+1] == '(' {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && (unicode.IsDigit(runes[end]) || unicode.IsLetter(runes[end]) || runes[end] == '.') {
+			end++
+		}
+		result.WriteString("(" + string(runes[i+1:end]) + ")")
+		i = end - 1
+	}
+	return result.String()
+}
+
+// Запятая между цифрами — десятичная, если она не разделяет аргументы
+// функции расширения с несколькими параметрами: 3,5 — 3.5, но dbsum(90,87).
+// Запятая перед ровно тремя цифрами (1,000 или 1,234,567) может разделять
+// разряды, поэтому такой текст не вставляется, а не угадывается. После нуля
+// (0,125) разрядов не бывает, и запятая остается десятичной.
+func (p *Presenter) replaceDecimalCommas(text string) (string, error) {
+	arity := map[string]int{}
+	for _, f := range p.model.Functions() {
+		arity[f.Name] = f.Arity
+	}
+
+	var calls []string
+	result := []byte(text)
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '(':
+			start := i
+			for start > 0 && unicode.IsLetter(rune(text[start-1])) {
+				start--
+			}
+			calls = append(calls, text[start:i])
+		case ')':
+			if len(calls) > 0 {
+				calls = calls[:len(calls)-1]
+			}
+		case ',':
+			separator := len(calls) > 0 && arity[calls[len(calls)-1]] > 1
+			if separator || i == 0 || i+1 >= len(text) || !isDigitByte(text[i-1]) || !isDigitByte(text[i+1]) {
+				continue
+			}
+			end := i + 1
+			for end < len(text) && isDigitByte(text[end]) {
+				end++
+			}
+			start := i - 1
+			for start > 0 && isDigitByte(text[start-1]) {
+				start--
+			}
+			if end-i-1 == 3 && strings.Trim(text[start:i], "0") != "" {
+				return "", fmt.Errorf("ambiguous comma at position %d: use a point for decimals and no thousands separators", i)
+			}
+			result[i] = '.'
+		}
+	}
+	return string(result), nil
+}
+
+func isDigitByte(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// Paste вставляет очищенный текст в позицию курсора; неверный текст
+// не попадает на дисплей, а возвращается ошибкой.
+func (p *Presenter) Paste(text string) error {
+	sanitized, err := p.SanitizeInput(text)
+	if err != nil {
+		return err
+	}
+	if len(p.state.Display)+len(sanitized) >= maxDisplayLength {
+		return fmt.Errorf("expression would be longer than %d characters", maxDisplayLength-1)
+	}
+
+	defer p.recordEdit()()
+	if p.state.Display == "0" || !helpers.IsValidInput(p.state.Display) {
+		p.setDisplay(sanitized)
+		return nil
+	}
+	p.insertAtCursor(sanitized)
+	return nil
+}
+
+// CopyExpression возвращает выражение дисплея как текст или в LaTeX.
+func (p *Presenter) CopyExpression(latex bool) (string, error) {
+	if !latex {
+		return p.state.Display, nil
+	}
+	return model.FormatLaTeX(expandCopyConstants(p.state.Display), model.ParsePercentMode(p.config.PercentMode))
+}
+
+// CopyResult вычисляет выражение дисплея без записи в историю и ans.
+func (p *Presenter) CopyResult(latex bool) (string, error) {
+	prepared, err := p.prepareExpression(expandConstants(p.state.Display))
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), evaluationTimeout)
+	defer cancel()
+	res, err := p.model.CalculateContext(ctx, prepared, xVars(p.state.X))
+	if err != nil {
+		return "", err
+	}
+
+	result := p.formatResult(res, true)
+	if !latex {
+		return result, nil
+	}
+	return model.FormatLaTeX(result, model.PercentCalculator)
+}
+
+// В LaTeX pi остается символом \pi, а не подставленным числом
+func expandCopyConstants(expression string) string {
+	return strings.ReplaceAll(expression, piValue, "pi")
+}
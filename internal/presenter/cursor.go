@@ -0,0 +1,128 @@
+package presenter
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Слова, которые курсор и <- обходят целиком (кроме имен функций)
+var wordTokens = []string{"mod", "ans", "pi"}
+
+func (p *Presenter) beforeCursor() string {
+	return p.state.Display[:p.state.Cursor]
+}
+
+func (p *Presenter) afterCursor() string {
+	return p.state.Display[p.state.Cursor:]
+}
+
+// insertAtCursor вставляет текст в позицию курсора и ставит курсор за ним.
+func (p *Presenter) insertAtCursor(text string) {
+	p.state.Display = p.beforeCursor() + text + p.afterCursor()
+	p.state.Cursor += len(text)
+	p.render()
+}
+
+func (p *Presenter) MoveCursorLeft() {
+	p.moveCursor(p.state.Cursor - p.tokenLengthBefore(p.beforeCursor()))
+}
+
+func (p *Presenter) MoveCursorRight() {
+	p.moveCursor(p.state.Cursor + p.tokenLengthAfter(p.afterCursor()))
+}
+
+func (p *Presenter) MoveCursorHome() {
+	p.moveCursor(0)
+}
+
+func (p *Presenter) MoveCursorEnd() {
+	p.moveCursor(len(p.state.Display))
+}
+
+// SetCursor ставит курсор на ближайшую к pos границу лексем (щелчок по дисплею).
+func (p *Presenter) SetCursor(pos int) {
+	nearest := 0
+	for boundary := 0; boundary < len(p.state.Display); {
+		boundary += p.tokenLengthAfter(p.state.Display[boundary:])
+		if abs(boundary-pos) < abs(nearest-pos) {
+			nearest = boundary
+		}
+	}
+	p.moveCursor(nearest)
+}
+
+func (p *Presenter) moveCursor(pos int) {
+	pos = max(0, min(pos, len(p.state.Display)))
+	if pos == p.state.Cursor {
+		return
+	}
+	p.state.Cursor = pos
+	p.render()
+}
+
+// tokenLengthBefore — длина лексемы, которая заканчивается в конце text:
+// sqrt( и pi стираются и пропускаются курсором целиком.
+func (p *Presenter) tokenLengthBefore(text string) int {
+	if text == "" {
+		return 0
+	}
+	if strings.HasSuffix(text, piValue) {
+		return len(piValue)
+	}
+
+	length := 1
+	for _, token := range p.wordTokens() {
+		if len(token) > length && strings.HasSuffix(text, token) {
+			length = len(token)
+		}
+	}
+	return length
+}
+
+func (p *Presenter) tokenLengthAfter(text string) int {
+	if text == "" {
+		return 0
+	}
+	if strings.HasPrefix(text, piValue) {
+		return len(piValue)
+	}
+
+	length := 1
+	for _, token := range p.wordTokens() {
+		if len(token) > length && strings.HasPrefix(text, token) {
+			length = len(token)
+		}
+	}
+	return length
+}
+
+func (p *Presenter) wordTokens() []string {
+	tokens := append([]string{}, wordTokens...)
+	for _, info := range p.FunctionCatalog("") {
+		tokens = append(tokens, info.Name+"(")
+	}
+	return tokens
+}
+
+func lastByteIs(text string, check func(rune) bool) bool {
+	return text != "" && check(rune(text[len(text)-1]))
+}
+
+func firstByteIs(text string, check func(rune) bool) bool {
+	return text != "" && check(rune(text[0]))
+}
+
+func isX(r rune) bool {
+	return r == 'x'
+}
+
+func isDigitOrX(r rune) bool {
+	return unicode.IsDigit(r) || r == 'x'
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
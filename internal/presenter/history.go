@@ -0,0 +1,194 @@
+package presenter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/helpers"
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/history"
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/model"
+)
+
+var errNoHistory = errors.New("history storage is not configured")
+
+// newHistoryStore выбирает хранилище истории: заданное в Config или
+// созданное по HistoryBackend и HistoryFilePath. nil — история не сохраняется.
+func newHistoryStore(config Config) history.Store {
+	if config.HistoryStore != nil {
+		return config.HistoryStore
+	}
+	if config.HistoryFilePath == "" {
+		return nil
+	}
+	store, err := history.OpenStore(config.HistoryBackend, config.HistoryFilePath, config.HistoryRetention)
+	if err != nil {
+		log.Printf("History is disabled: %v", err)
+		return nil
+	}
+	return store
+}
+
+func (p *Presenter) history() (history.Store, error) {
+	if p.historyStore == nil {
+		return nil, errNoHistory
+	}
+	return p.historyStore, nil
+}
+
+// saveHistory дополняет запись значением x и настройками и дописывает ее в историю.
+func (p *Presenter) saveHistory(entry history.Entry) {
+	if p.historyStore == nil {
+		log.Println("History storage is not configured. Skipping save.")
+		return
+	}
+
+	if entry.Kind != history.KindCredit {
+		entry.X = p.state.X
+	}
+	entry.Mode = history.Mode{
+		Exact:      p.useExact,
+		Fraction:   p.showFraction,
+		Scientific: p.useScientific,
+		Percent:    p.config.PercentMode,
+	}
+	if err := p.historyStore.Append(entry); err != nil {
+		log.Printf("Failed to write to history: %v", err)
+	}
+}
+
+// RecordPlot сохраняет в истории выражение, для которого строится график,
+// если промежуточные записи не отключены.
+func (p *Presenter) RecordPlot() {
+	if p.state.Display != "0" && !p.config.HistorySkipIntermediate {
+		p.saveHistory(history.Entry{Kind: history.KindPlot, Expression: p.state.Display})
+	}
+}
+
+// HistoryEntries возвращает записи истории в порядке вычисления.
+func (p *Presenter) HistoryEntries() ([]history.Entry, error) {
+	if p.historyStore == nil {
+		return nil, nil
+	}
+	return p.historyStore.Entries()
+}
+
+// SearchHistory отбирает записи фильтром и группирует их по дням.
+func (p *Presenter) SearchHistory(filter history.Filter) ([]history.Group, error) {
+	entries, err := p.HistoryEntries()
+	if err != nil {
+		return nil, err
+	}
+	entries, err = filter.Apply(entries)
+	if err != nil {
+		return nil, err
+	}
+	return history.GroupByDay(entries), nil
+}
+
+// PinnedHistory возвращает закрепленные записи, подходящие под фильтр.
+func (p *Presenter) PinnedHistory(filter history.Filter) ([]history.Entry, error) {
+	entries, err := p.HistoryEntries()
+	if err != nil {
+		return nil, err
+	}
+	filter.Pinned = true
+	return filter.Apply(entries)
+}
+
+// PinHistoryEntry закрепляет запись: ограничения истории ее не удаляют.
+func (p *Presenter) PinHistoryEntry(entry history.Entry, pinned bool) error {
+	store, err := p.history()
+	if err != nil {
+		return err
+	}
+	return store.SetPinned(entry, pinned)
+}
+
+func (p *Presenter) SetHistoryNote(entry history.Entry, note string) error {
+	store, err := p.history()
+	if err != nil {
+		return err
+	}
+	return store.SetNote(entry, note)
+}
+
+// ExportHistory выгружает отобранные фильтром записи в выбранном формате.
+func (p *Presenter) ExportHistory(w io.Writer, format history.ExportFormat, filter history.Filter) error {
+	entries, err := p.HistoryEntries()
+	if err != nil {
+		return err
+	}
+	entries, err = filter.Apply(entries)
+	if err != nil {
+		return err
+	}
+	return history.Export(w, entries, format)
+}
+
+// ImportHistory добавляет в историю записи из файла другого компьютера;
+// уже сохраненные записи пропускаются.
+func (p *Presenter) ImportHistory(path string) (int, error) {
+	store, err := p.history()
+	if err != nil {
+		return 0, err
+	}
+	entries, err := history.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return store.Merge(entries)
+}
+
+// InsertExpression вставляет выражение (например, из истории) в позицию курсора.
+// Составное выражение берется в скобки, чтобы не изменился порядок действий.
+func (p *Presenter) InsertExpression(expression string) {
+	defer p.recordEdit()()
+
+	if p.state.Display == "0" || !helpers.IsValidInput(p.state.Display) {
+		p.setDisplay(expression)
+		return
+	}
+	if tokens, err := model.Tokenize(expression); err != nil || len(tokens) > 1 {
+		expression = "(" + expression + ")"
+	}
+	if len(p.state.Display)+len(expression) >= maxDisplayLength {
+		return
+	}
+	p.insertAtCursor(expression)
+}
+
+// WatchHistory сообщает об изменениях истории, в том числе сделанных другим
+// запущенным калькулятором. Возвращаемая функция прекращает наблюдение.
+func (p *Presenter) WatchHistory(onChange func()) (func(), error) {
+	store, err := p.history()
+	if err != nil {
+		return nil, err
+	}
+	return store.Watch(onChange)
+}
+
+func (p *Presenter) ClearHistory() error {
+	store, err := p.history()
+	if err != nil {
+		return err
+	}
+	return store.Clear()
+}
+
+func formatCreditResults(results map[string]float64) string {
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%.2f", name, results[name])
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,130 @@
+package presenter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Команды редактора, у которых нет своей кнопки
+const (
+	ActionCursorLeft  = "Left"
+	ActionCursorRight = "Right"
+	ActionCursorHome  = "Home"
+	ActionCursorEnd   = "End"
+	ActionUndo        = "Undo"
+	ActionRedo        = "Redo"
+
+	// Буфер обмена: действия выполняет интерфейс, у которого есть доступ к нему
+	ActionCopyResult          = "CopyResult"
+	ActionCopyExpression      = "CopyExpression"
+	ActionCopyResultLaTeX     = "CopyResultLaTeX"
+	ActionCopyExpressionLaTeX = "CopyExpressionLaTeX"
+	ActionPaste               = "Paste"
+)
+
+// KeyBindings сопоставляет клавише действие: подпись кнопки ("sin", "=", "Plot")
+// или команду редактора (Left, Undo). Клавиша задается символом ("7", "s"),
+// именем клавиши ("Return", "Escape", "F1") или сочетанием ("Ctrl+P").
+type KeyBindings map[string]string
+
+// Символы, которые вводятся кнопкой с такой же подписью
+const typedButtonLabels = "0123456789+-*/^%().,xe"
+
+func DefaultKeyBindings() KeyBindings {
+	bindings := KeyBindings{
+		// Однобуквенные имена функций — те же, что понимает ядро
+		"q": "sqrt", "s": "sin", "c": "cos", "t": "tan",
+		"S": "asin", "C": "acos", "T": "atan",
+		"l": "ln", "L": "log", "p": "pi", "m": "mod", "a": "Ans",
+		"X": "x<-",
+
+		"Return":    "=",
+		"KP_Enter":  "=",
+		"=":         "=",
+		"BackSpace": "<-",
+		"Escape":    "AC",
+
+		"Left":         ActionCursorLeft,
+		"Right":        ActionCursorRight,
+		"Home":         ActionCursorHome,
+		"End":          ActionCursorEnd,
+		"Ctrl+Z":       ActionUndo,
+		"Ctrl+Shift+Z": ActionRedo,
+		"Ctrl+Y":       ActionRedo,
+
+		"Ctrl+C":           ActionCopyResult,
+		"Ctrl+Shift+C":     ActionCopyExpression,
+		"Ctrl+Alt+C":       ActionCopyExpressionLaTeX,
+		"Ctrl+Alt+Shift+C": ActionCopyResultLaTeX,
+		"Ctrl+V":           ActionPaste,
+		"Shift+Insert":     ActionPaste,
+
+		"F1":     "Help",
+		"Ctrl+P": "Plot",
+		"Ctrl+H": "History",
+		"Ctrl+K": "Credit",
+		"Ctrl+F": "f(x)",
+		"Ctrl+M": "MS",
+	}
+	for _, r := range typedButtonLabels {
+		bindings[string(r)] = string(r)
+	}
+	return bindings
+}
+
+// LoadKeyBindings дополняет привязки по умолчанию JSON-объектом из файла
+// {"клавиша": "действие"}; пустое действие отключает клавишу.
+func LoadKeyBindings(path string) (KeyBindings, error) {
+	bindings := DefaultKeyBindings()
+	if path == "" {
+		return bindings, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return bindings, err
+	}
+	var overrides map[string]string
+	if err := json.Unmarshal(content, &overrides); err != nil {
+		return bindings, fmt.Errorf("invalid key bindings file '%s': %w", path, err)
+	}
+
+	for key, action := range overrides {
+		if action == "" {
+			delete(bindings, key)
+		} else {
+			bindings[key] = action
+		}
+	}
+	return bindings, nil
+}
+
+func (p *Presenter) KeyBindings() KeyBindings {
+	if p.config.KeyBindings == nil {
+		return DefaultKeyBindings()
+	}
+	return p.config.KeyBindings
+}
+
+// RunEditorAction выполняет команду редактора; false — это не команда
+// редактора, и действие должна выполнить кнопка с такой подписью.
+func (p *Presenter) RunEditorAction(action string) bool {
+	switch action {
+	case ActionCursorLeft:
+		p.MoveCursorLeft()
+	case ActionCursorRight:
+		p.MoveCursorRight()
+	case ActionCursorHome:
+		p.MoveCursorHome()
+	case ActionCursorEnd:
+		p.MoveCursorEnd()
+	case ActionUndo:
+		p.Undo()
+	case ActionRedo:
+		p.Redo()
+	default:
+		return false
+	}
+	return true
+}
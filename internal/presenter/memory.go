@@ -0,0 +1,204 @@
+package presenter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/helpers"
+)
+
+const (
+	defaultMemorySlot = "M"
+	maxAnswers        = 100
+)
+
+var (
+	answerPattern   = regexp.MustCompile(`\bans(\d*)\b`)
+	slotNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+)
+
+type memoryState struct {
+	Slots   map[string]float64 `json:"slots"`
+	Answers []float64          `json:"answers"`
+}
+
+// loadMemory возвращает и содержимое файла: SaveMemory не переписывает его без изменений.
+func loadMemory(path string) (memoryState, []byte) {
+	state := memoryState{Slots: map[string]float64{}}
+	if path == "" {
+		return state, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Failed to read memory file '%s': %v", path, err)
+		}
+		return state, nil
+	}
+
+	if err := json.Unmarshal(content, &state); err != nil {
+		log.Printf("Failed to parse memory file '%s': %v", path, err)
+		return memoryState{Slots: map[string]float64{}}, nil
+	}
+	if state.Slots == nil {
+		state.Slots = map[string]float64{}
+	}
+	return state, content
+}
+
+// SaveMemory записывает память в MEMORY_FILE_PATH. Ячейки сохраняются сразу
+// при изменении, а ans — при выходе из программы, а не на каждое "=".
+// Файл не переписывается, если его содержимое не изменилось.
+func (p *Presenter) SaveMemory() {
+	memoryFilePath := p.config.MemoryFilePath
+	if memoryFilePath == "" {
+		return
+	}
+
+	content, err := json.MarshalIndent(p.memory, "", "  ")
+	if err != nil {
+		log.Printf("Failed to encode memory: %v", err)
+		return
+	}
+	if bytes.Equal(content, p.memorySaved) {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(memoryFilePath), 0755); err != nil {
+		log.Printf("Failed to create memory directory: %v", err)
+		return
+	}
+	if err := os.WriteFile(memoryFilePath, content, 0644); err != nil {
+		log.Printf("Failed to write memory file '%s': %v", memoryFilePath, err)
+		return
+	}
+	p.memorySaved = content
+}
+
+func (p *Presenter) rememberAnswer(value float64) {
+	p.memory.Answers = append([]float64{value}, p.memory.Answers...)
+	if len(p.memory.Answers) > maxAnswers {
+		p.memory.Answers = p.memory.Answers[:maxAnswers]
+	}
+}
+
+// ans — последний результат, ans1, ans2, ... — более ранние.
+func (p *Presenter) substituteAnswers(expression string) (string, error) {
+	var substituteErr error
+	result := answerPattern.ReplaceAllStringFunc(expression, func(match string) string {
+		index := 0
+		if digits := answerPattern.FindStringSubmatch(match)[1]; digits != "" {
+			index, _ = strconv.Atoi(digits)
+		}
+		if index >= len(p.memory.Answers) {
+			substituteErr = fmt.Errorf("%s is not available", match)
+			return match
+		}
+		return "(" + formatOperand(p.memory.Answers[index]) + ")"
+	})
+	return result, substituteErr
+}
+
+func formatOperand(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func (p *Presenter) evaluateDisplay() (float64, error) {
+	currentDisplay := p.state.Display
+	if !helpers.IsValidInput(currentDisplay) {
+		return 0, errors.New("nothing to evaluate")
+	}
+
+	currentDisplay, err := p.prepareExpression(expandConstants(currentDisplay))
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), evaluationTimeout)
+	defer cancel()
+	return p.model.CalculateContext(ctx, currentDisplay, xVars(p.state.X))
+}
+
+func (p *Presenter) MemoryClear() {
+	delete(p.memory.Slots, defaultMemorySlot)
+	p.SaveMemory()
+}
+
+func (p *Presenter) MemoryRecall() {
+	p.MemoryRecallSlot(defaultMemorySlot)
+}
+
+func (p *Presenter) MemoryAdd() {
+	p.updateMemorySlot(defaultMemorySlot, 1)
+}
+
+func (p *Presenter) MemorySubtract() {
+	p.updateMemorySlot(defaultMemorySlot, -1)
+}
+
+func (p *Presenter) updateMemorySlot(name string, sign float64) {
+	value, err := p.evaluateDisplay()
+	if err != nil {
+		p.setDisplay(err.Error())
+		return
+	}
+	p.memory.Slots[name] += sign * value
+	p.SaveMemory()
+}
+
+func (p *Presenter) MemoryStore(name string) error {
+	if !slotNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid memory slot name %q", name)
+	}
+	value, err := p.evaluateDisplay()
+	if err != nil {
+		return err
+	}
+	p.memory.Slots[name] = value
+	p.SaveMemory()
+	return nil
+}
+
+func (p *Presenter) MemoryRecallSlot(name string) {
+	defer p.recordEdit()()
+
+	value, ok := p.memory.Slots[name]
+	if !ok {
+		return
+	}
+
+	operand := formatOperand(value)
+	if value < 0 || strings.Contains(operand, "e") {
+		operand = "(" + operand + ")"
+	}
+	p.AppendButtonText(operand)
+}
+
+func (p *Presenter) MemoryDelete(name string) {
+	delete(p.memory.Slots, name)
+	p.SaveMemory()
+}
+
+func (p *Presenter) MemorySlots() []string {
+	names := make([]string, 0, len(p.memory.Slots))
+	for name := range p.memory.Slots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (p *Presenter) MemoryValue(name string) (float64, bool) {
+	value, ok := p.memory.Slots[name]
+	return value, ok
+}
@@ -1,15 +1,20 @@
 package presenter
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
-	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 
 	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/helpers"
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/history"
 	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/model"
 )
 
@@ -17,77 +22,301 @@ const (
 	eLiteral = "e"
 	piValue  = "3.14159265359"
 	eValue   = "2.71828182846"
+
+	maxFractionDenominator = 1000000
+	displayPrecision       = 15
+	evaluationTimeout      = 5 * time.Second
+	floatFallbackStatus    = "≈ floating-point result"
+
+	// За этими порядками результат показывается в экспоненциальной форме,
+	// иначе 1e300 или 1e-30 не помещаются на дисплей
+	plainExponentMax = 15
+	plainExponentMin = -6
 )
 
+// ViewInterface — только отрисовка: состояние ввода хранит презентер.
+// RenderPreview вызывается из фоновой горутины, когда готов предпросмотр результата.
 type ViewInterface interface {
-	UpdatedisplayLabelWithText(inputText string)
-	UpdateXLabelWithText(inputText string)
-	GetUseScientific() bool
-	GetHistoryFilePath() string
-	GetCounter() int
-	GetVariableXLabel() string
-	GetDisplayLabel() string
+	Render(state State)
+	RenderPreview(preview string)
+	RefreshEngineFeatures()
 }
 
 type Presenter struct {
-	view  ViewInterface
-	model *model.Model
+	view        ViewInterface
+	model       *model.Model
+	config      Config
+	state       State
+	memory      memoryState
+	memorySaved []byte
+	undo        []editorState
+	redo        []editorState
+
+	historyStore history.Store
+	sessionName  string
+	workspace    workspace
+
+	previewMu         sync.Mutex
+	previewRenderMu   sync.Mutex
+	preview           string
+	previewGeneration uint64
+	previewTimer      *time.Timer
+
+	useScientific bool
+	useExact      bool
+	showFraction  bool
 }
 
-func NewPresenter(v ViewInterface, m *model.Model) *Presenter {
+// NewPresenter создает презентер; v может быть nil, если интерфейса нет.
+func NewPresenter(v ViewInterface, m *model.Model, config Config) *Presenter {
+	memory, memorySaved := loadMemory(config.MemoryFilePath)
 	return &Presenter{
-		view:  v,
-		model: m,
+		view:        v,
+		model:       m,
+		config:      config,
+		state:       State{Display: "0", Cursor: 1, X: "0"},
+		memory:      memory,
+		memorySaved: memorySaved,
+
+		historyStore: newHistoryStore(config),
+		sessionName:  DefaultSession,
+		workspace:    newWorkspace(),
 	}
 }
 
 func (p *Presenter) EvaluateExpression(expression *string, xValue string) {
-	p.view.UpdatedisplayLabelWithText(p.calculateResult(expression, xValue))
+	result, _ := p.calculateResult(expression, xValue, true)
+	p.setDisplay(result)
 }
 
 func (p *Presenter) EvaluateWithX(expression *string, xValue string) {
-	p.view.UpdateXLabelWithText(fmt.Sprint(p.calculateResult(expression, xValue)))
+	result, _ := p.calculateResult(expression, xValue, false)
+	p.setX(fmt.Sprint(result))
 }
 
-func (p *Presenter) calculateResult(expression *string, xValue string) string {
-	var result string
-	if res, err := p.model.Calculate(expression, xValue); err != nil {
-		result = err.Error()
-	} else {
-		if p.view.GetUseScientific() {
-			result = strconv.FormatFloat(res, 'e', 8, 64)
-		} else {
-			result = strconv.FormatFloat(res, 'f', -1, 64)
+func (p *Presenter) prepareExpression(expression string) (string, error) {
+	expression, err := p.substituteAnswers(expression)
+	if err != nil {
+		return "", err
+	}
+	return model.TranslatePercent(expression, model.ParsePercentMode(p.config.PercentMode))
+}
+
+// calculateResult возвращает текст для дисплея: результат или сообщение
+// об ошибке, которая возвращается и отдельно.
+func (p *Presenter) calculateResult(expression *string, xValue string, toDisplay bool) (string, error) {
+	prepared, err := p.prepareExpression(*expression)
+	if err != nil {
+		return err.Error(), err
+	}
+	*expression = prepared
+
+	ctx, cancel := context.WithTimeout(context.Background(), evaluationTimeout)
+	defer cancel()
+
+	res, err := p.model.CalculateContext(ctx, *expression, xVars(xValue))
+	if err != nil {
+		return err.Error(), err
+	}
+	if toDisplay {
+		p.rememberAnswer(res)
+	}
+	return p.formatResult(res, toDisplay), nil
+}
+
+func expandConstants(expression string) string {
+	expression = helpers.ReplaceIdentifier(expression, "pi", piValue)
+	return helpers.ReplaceEConstant(expression)
+}
+
+func xVars(xValue string) map[string]float64 {
+	x, err := strconv.ParseFloat(strings.TrimSpace(xValue), 64)
+	if err != nil {
+		return nil
+	}
+	return map[string]float64{"x": x}
+}
+
+func (p *Presenter) CheckIterations(n int) error {
+	return p.model.Limits().CheckIterations(n)
+}
+
+func (p *Presenter) formatResult(res float64, allowFraction bool) string {
+	return formatValue(res, allowFraction && p.showFraction, p.useScientific)
+}
+
+func formatValue(res float64, fraction, scientific bool) string {
+	if fraction {
+		if rat, ok := helpers.ApproximateFraction(res, maxFractionDenominator); ok {
+			return rat.RatString()
+		}
+	}
+	if scientific {
+		return strconv.FormatFloat(res, 'e', 8, 64)
+	}
+	if res == 0 {
+		return "0"
+	}
+
+	// Модель отдает double целиком; на дисплей выводится displayPrecision
+	// значащих цифр, без хвостов двоичного представления (0.1+0.2 = 0.30000000000000004).
+	// Цифры и порядок берутся из одного форматирования самого значения.
+	mantissa, exponentText, _ := strings.Cut(strconv.FormatFloat(res, 'e', displayPrecision-1, 64), "e")
+	exponent, _ := strconv.Atoi(exponentText)
+	sign, mantissa := "", strings.TrimPrefix(mantissa, "-")
+	if res < 0 {
+		sign = "-"
+	}
+	digits := strings.TrimRight(strings.Replace(mantissa, ".", "", 1), "0")
+
+	switch {
+	case exponent >= plainExponentMax || exponent < plainExponentMin:
+		if len(digits) > 1 {
+			digits = digits[:1] + "." + digits[1:]
 		}
+		return fmt.Sprintf("%s%se%+03d", sign, digits, exponent)
+	case exponent < 0:
+		return sign + "0." + strings.Repeat("0", -exponent-1) + digits
+	case len(digits) <= exponent+1:
+		return sign + digits + strings.Repeat("0", exponent+1-len(digits))
+	default:
+		return sign + digits[:exponent+1] + "." + digits[exponent+1:]
 	}
-	return result
 }
 
-func (p *Presenter) CalculatePlotResult(expression *string, xValue string) (string, error) {
-	if expression == nil || *expression == "" {
-		return "", fmt.Errorf("expression is empty")
+func (p *Presenter) formatExactResult(res *big.Rat) string {
+	if res.IsInt() || p.showFraction {
+		return res.RatString()
 	}
+	value, _ := res.Float64()
+	return p.formatResult(value, false)
+}
 
-	res, err := p.model.Calculate(expression, xValue)
+// EvaluateBatch вычисляет выражение дисплея сразу для всех значений x
+// (графики, таблицы значений, экспорт).
+func (p *Presenter) EvaluateBatch(ctx context.Context, expression string, xs []float64) ([]float64, []error) {
+	prepared, err := p.prepareExpression(expandConstants(expression))
 	if err != nil {
-		return "", fmt.Errorf("calculation error at x=%s: %v", xValue, err)
+		errs := make([]error, len(xs))
+		for i := range errs {
+			errs[i] = err
+		}
+		return make([]float64, len(xs)), errs
+	}
+	return p.model.EvaluateBatchContext(ctx, prepared, xs)
+}
+
+// ReportEngineReload показывает результат перезагрузки ядра после пересборки библиотеки.
+func (p *Presenter) ReportEngineReload(err error) {
+	if err != nil {
+		p.setStatus("Engine reload failed: " + err.Error())
+		return
+	}
+	p.setStatus("Engine reloaded")
+	p.refreshEngineFeatures()
+}
+
+func (p *Presenter) SupportsFunction(name string) bool {
+	return p.model.Capabilities().HasFunction(name)
+}
+
+func (p *Presenter) SupportsOperator(op string) bool {
+	return p.model.Capabilities().HasOperator(op)
+}
+
+func (p *Presenter) SupportsCredit() bool {
+	return p.model.Capabilities().HasMode(model.ModeCredit)
+}
+
+type FunctionInfo struct {
+	Name      string
+	Signature string
+	Doc       string
+	Source    string
+}
+
+// FunctionCatalog перечисляет функции ядра и расширений, имена которых
+// начинаются с prefix, — для выбора функции и подсказок при вводе.
+func (p *Presenter) FunctionCatalog(prefix string) []FunctionInfo {
+	var catalog []FunctionInfo
+	for _, name := range p.model.Capabilities().Functions {
+		catalog = append(catalog, FunctionInfo{Name: name, Signature: name + "(x)", Source: "engine"})
+	}
+	for _, f := range p.model.Functions() {
+		catalog = append(catalog, FunctionInfo{Name: f.Name, Signature: functionSignature(f), Doc: f.Doc, Source: f.Pack})
 	}
 
-	if !helpers.IsValidInput(fmt.Sprint(res)) {
-		return "", fmt.Errorf("invalid result (NaN, Inf, or invalid input) at x=%s", xValue)
+	var matches []FunctionInfo
+	for _, info := range catalog {
+		if strings.HasPrefix(info.Name, prefix) {
+			matches = append(matches, info)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Name < matches[j].Name })
+	return matches
+}
+
+func functionSignature(f model.Function) string {
+	args := make([]string, f.Arity)
+	for i := range args {
+		args[i] = string(rune('a' + i))
+	}
+	return f.Name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// ExtensionsHelp — раздел справки о функциях расширений в формате Markdown.
+func (p *Presenter) ExtensionsHelp() string {
+	functions := p.model.Functions()
+	if len(functions) == 0 {
+		return ""
 	}
 
-	bigRes := new(big.Float).SetFloat64(res)
-	bigRes.SetPrec(200)
+	var help strings.Builder
+	help.WriteString("### Функции расширений\n\n")
+	for _, f := range functions {
+		fmt.Fprintf(&help, "  - **%s** — %s", functionSignature(f), f.Doc)
+		if f.Pack != "" {
+			fmt.Fprintf(&help, " (%s)", f.Pack)
+		}
+		help.WriteString("\n")
+	}
+	return help.String()
+}
 
-	if bigRes.String() == "NaN" || bigRes.String() == "Inf" || bigRes.String() == "-Inf" {
-		return "", fmt.Errorf("invalid result after big.Float conversion at x=%s", xValue)
+func (p *Presenter) ReportExtensionErrors(errs []error) {
+	if len(errs) == 0 {
+		return
 	}
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		log.Printf("Extension error: %v", err)
+		messages[i] = err.Error()
+	}
+	p.setStatus("Extensions: " + strings.Join(messages, "; "))
+}
 
-	return bigRes.Text('f', 15), nil
+func (p *Presenter) AboutText() string {
+	capabilities := p.model.Capabilities()
+	return fmt.Sprintf("SmartCalc v3.0\n\nEngine version: %s\nFunctions: %s\nOperators: %s\nModes: %s",
+		p.model.Version(),
+		strings.Join(capabilities.Functions, ", "),
+		strings.Join(capabilities.Operators, " "),
+		strings.Join(capabilities.Modes, ", "))
 }
 
 func (p *Presenter) CalculateCredit(creditType string, sum, duration, rate float64) (map[string]float64, error) {
+	results, err := p.calculateCredit(creditType, sum, duration, rate)
+	if err == nil {
+		p.saveHistory(history.Entry{
+			Kind:       history.KindCredit,
+			Expression: fmt.Sprintf("%s: sum=%g, term=%g, rate=%g", creditType, sum, duration, rate),
+			Result:     formatCreditResults(results),
+		})
+	}
+	return results, err
+}
+
+func (p *Presenter) calculateCredit(creditType string, sum, duration, rate float64) (map[string]float64, error) {
 	switch creditType {
 	case "Annuity":
 		monthly, overpay, total, err := p.model.CreditAnnuity(sum, duration, rate)
@@ -116,212 +345,249 @@ func (p *Presenter) CalculateCredit(creditType string, sum, duration, rate float
 }
 
 func (p *Presenter) HandleEInput() {
-	currentDisplay := p.view.GetDisplayLabel()
-	if p.view.GetCounter() > 0 && unicode.IsDigit(rune(currentDisplay[p.view.GetCounter()-1])) {
-		p.view.UpdatedisplayLabelWithText(currentDisplay + eLiteral)
-		return
-	}
+	defer p.recordEdit()()
 
-	if currentDisplay[p.view.GetCounter()-1] == '+' || currentDisplay[p.view.GetCounter()-1] == '-' ||
-		currentDisplay[p.view.GetCounter()-1] == '*' || currentDisplay[p.view.GetCounter()-1] == '/' ||
-		currentDisplay[p.view.GetCounter()-1] == '(' {
-		p.view.UpdatedisplayLabelWithText(currentDisplay + eLiteral)
+	before := p.beforeCursor()
+	if before == "" || lastByteIs(before, unicode.IsDigit) || strings.ContainsAny(before[len(before)-1:], "+-*/(") {
+		p.insertAtCursor(eLiteral)
 		return
 	}
 
-	if unicode.IsLetter(rune(currentDisplay[p.view.GetCounter()-1])) {
-		p.view.UpdatedisplayLabelWithText("error")
-		return
-	}
-
-	p.view.UpdatedisplayLabelWithText("error")
+	p.setDisplay("error")
 }
 
 func (p *Presenter) AppendOperator(operator string) {
-	if p.view.GetCounter() < 256 {
-		currentDisplay := p.view.GetDisplayLabel()
+	defer p.recordEdit()()
+
+	if len(p.state.Display) < maxDisplayLength {
+		currentDisplay := p.state.Display
+		before := p.beforeCursor()
 
 		if operator == "pi" {
-			if p.view.GetCounter() > 0 && unicode.IsLetter(rune(currentDisplay[p.view.GetCounter()-1])) {
+			if lastByteIs(before, unicode.IsLetter) {
 				return
 			}
 
-			if p.view.GetCounter() > 1 && p.view.GetCounter() <= len(currentDisplay) && unicode.IsDigit(rune(currentDisplay[p.view.GetCounter()-1])) {
-				p.view.UpdatedisplayLabelWithText("error")
+			if len(currentDisplay) > 1 && lastByteIs(before, unicode.IsDigit) {
+				p.setDisplay("error")
 				return
 			}
 
 			operator = piValue
 		}
 
-		if operator == "e" {
-			if currentDisplay == "0" || !helpers.IsValidInput(currentDisplay) {
-				currentDisplay = ""
-			}
-			p.view.UpdatedisplayLabelWithText(eLiteral)
+		if operator == "e" && currentDisplay == "0" {
+			currentDisplay = ""
 		}
 
-		if !helpers.IsValidInput(currentDisplay) {
-			currentDisplay = ""
+		if currentDisplay == "" || !helpers.IsValidInput(currentDisplay) {
+			p.setDisplay(operator)
+			return
 		}
-		p.view.UpdatedisplayLabelWithText(currentDisplay + operator)
+		p.insertAtCursor(operator)
 	}
 }
 
 func (p *Presenter) AppendButtonText(inputText string) {
-	if p.view.GetCounter() < 256 {
-		currentDisplay := p.view.GetDisplayLabel()
+	defer p.recordEdit()()
 
-		if p.view.GetCounter() >= 2 && currentDisplay[p.view.GetCounter()-2:p.view.GetCounter()] == "pi" && unicode.IsDigit(rune(inputText[0])) {
-			p.view.UpdatedisplayLabelWithText("error")
+	if len(p.state.Display) < maxDisplayLength {
+		currentDisplay := p.state.Display
+		before := p.beforeCursor()
+
+		if strings.HasSuffix(before, "pi") && unicode.IsDigit(rune(inputText[0])) {
+			p.setDisplay("error")
 			return
 		}
 
-		if (inputText != "-" && inputText != "(" && inputText != ")") && currentDisplay[p.view.GetCounter()-1] == 'x' {
+		if (inputText != "-" && inputText != "(" && inputText != ")" && inputText != ",") &&
+			(lastByteIs(before, isX) || firstByteIs(p.afterCursor(), isX)) {
 			return
 		}
 
 		if currentDisplay == "0" || !helpers.IsValidInput(currentDisplay) {
-			currentDisplay = ""
+			p.setDisplay(inputText)
+			return
 		}
-		p.view.UpdatedisplayLabelWithText(currentDisplay + inputText)
+		p.insertAtCursor(inputText)
 	}
 }
 
 func (p *Presenter) ResetButton() {
-	p.view.UpdatedisplayLabelWithText("0")
+	defer p.recordEdit()()
+
+	p.setDisplay("0")
 }
 
 func (p *Presenter) AddDecimalPoint() {
-	currentDisplay := p.view.GetDisplayLabel()
+	defer p.recordEdit()()
+
+	currentDisplay := p.state.Display
 
 	if len(currentDisplay) == 0 {
-		p.view.UpdatedisplayLabelWithText("0.")
+		p.setDisplay("0.")
 		return
 	}
 
+	// Число может продолжаться и слева, и справа от курсора
+	before, after := p.beforeCursor(), p.afterCursor()
 	hasDecimal := false
-	for i := len(currentDisplay) - 1; i >= 0; i-- {
-		if currentDisplay[i] == '.' {
-			hasDecimal = true
+	for i := len(before) - 1; i >= 0 && !hasDecimal; i-- {
+		if !unicode.IsDigit(rune(before[i])) {
+			hasDecimal = before[i] == '.'
 			break
 		}
-		if !unicode.IsDigit(rune(currentDisplay[i])) {
+	}
+	for i := 0; i < len(after) && !hasDecimal; i++ {
+		if !unicode.IsDigit(rune(after[i])) {
+			hasDecimal = after[i] == '.'
 			break
 		}
 	}
 
 	if !hasDecimal {
-		p.view.UpdatedisplayLabelWithText(currentDisplay + ".")
+		p.insertAtCursor(".")
 	}
 }
 
 func (p *Presenter) DeleteButton() {
-	currentDisplay := p.view.GetDisplayLabel()
+	defer p.recordEdit()()
+
+	currentDisplay := p.state.Display
 	if len(currentDisplay) == 0 || currentDisplay == "0" {
-		p.view.UpdatedisplayLabelWithText("0")
+		p.setDisplay("0")
 		return
 	}
 
-	if len(currentDisplay) >= len(piValue) && currentDisplay[len(currentDisplay)-len(piValue):] == piValue {
-		currentDisplay = currentDisplay[:len(currentDisplay)-len(piValue)]
-	} else {
-		currentDisplay = currentDisplay[:len(currentDisplay)-1]
+	before := p.beforeCursor()
+	if before == "" {
+		return
 	}
+	before = before[:len(before)-p.tokenLengthBefore(before)]
 
-	if len(currentDisplay) == 0 {
-		currentDisplay = "0"
+	if before == "" && p.afterCursor() == "" {
+		p.setDisplay("0")
+		return
 	}
-	p.view.UpdatedisplayLabelWithText(currentDisplay)
+	p.state.Display = before + p.afterCursor()
+	p.state.Cursor = len(before)
+	p.render()
 }
 
 func (p *Presenter) AppendX() {
-	if p.view.GetCounter() < 256 {
-		currentDisplay := p.view.GetDisplayLabel()
-		if currentDisplay[p.view.GetCounter()-1] != 'x' && !unicode.IsDigit(rune(currentDisplay[p.view.GetCounter()-1])) {
-			p.view.UpdatedisplayLabelWithText(currentDisplay + "x")
+	defer p.recordEdit()()
+
+	if len(p.state.Display) < maxDisplayLength {
+		if p.state.Display == "0" {
+			p.setDisplay("x")
+			return
 		}
-		if currentDisplay == "0" {
-			p.view.UpdatedisplayLabelWithText("x")
+		if !lastByteIs(p.beforeCursor(), isDigitOrX) && !firstByteIs(p.afterCursor(), isDigitOrX) {
+			p.insertAtCursor("x")
 		}
 	}
 }
 
 func (p *Presenter) InitializeXButton() {
-	currentDisplay := p.view.GetDisplayLabel()
+	defer p.recordEdit()()
+
+	currentDisplay := p.state.Display
 	if helpers.IsValidInput(currentDisplay) {
-		p.EvaluateWithX(&currentDisplay, p.view.GetVariableXLabel())
+		p.EvaluateWithX(&currentDisplay, p.state.X)
 	} else {
-		p.view.UpdatedisplayLabelWithText("0")
+		p.setDisplay("0")
 	}
 }
 
 func (p *Presenter) InverseSign() {
-	currentDisplay := p.view.GetDisplayLabel()
+	defer p.recordEdit()()
+
+	currentDisplay := p.state.Display
 	if !helpers.IsValidInput(currentDisplay) {
 		currentDisplay = "0"
 	}
 
-	if currentDisplay != "0" {
-		currentDisplay = "(" + currentDisplay + ")*(-1)"
-	}
-	if currentDisplay != "0" {
-		p.SaveHistory()
-	}
-	p.EvaluateExpression(&currentDisplay, p.view.GetVariableXLabel())
-}
-
-func (p *Presenter) SaveHistory() {
-	historyFilePath := p.view.GetHistoryFilePath()
-	if historyFilePath == "" {
-		log.Println("History file path is not set. Skipping save.")
+	if currentDisplay == "0" {
+		p.setDisplay("0")
 		return
 	}
 
-	file, err := os.OpenFile(historyFilePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0666)
-	if err != nil {
-		log.Printf("Failed to open history file '%s': %v", historyFilePath, err)
-		return
-	}
-	defer file.Close()
-
-	_, err = file.WriteString(fmt.Sprintf("%s\n", p.view.GetDisplayLabel()))
-	if err != nil {
-		log.Printf("Failed to write to history file '%s': %v", historyFilePath, err)
+	// Как и у "=", в историю попадает только успешное вычисление вместе с результатом
+	expression := "(" + currentDisplay + ")*(-1)"
+	currentDisplay = expression
+	result, err := p.calculateResult(&currentDisplay, p.state.X, true)
+	p.setDisplay(result)
+	if err == nil && !p.config.HistorySkipIntermediate {
+		p.saveHistory(history.Entry{Kind: history.KindExpression, Expression: expression, Result: result})
 	}
 }
 
 func (p *Presenter) EvaluateAndProcessExpression() {
-	currentDisplay := p.view.GetDisplayLabel()
+	defer p.recordEdit()()
 
-	if strings.Contains(currentDisplay, ")") {
-		for i := 0; i < len(currentDisplay)-1; i++ {
-			if currentDisplay[i] == ')' && unicode.IsLetter(rune(currentDisplay[i+1])) {
-				p.view.UpdatedisplayLabelWithText("error")
-				return
-			}
-		}
+	currentDisplay := p.state.Display
+
+	// Ошибки разбора покажет само вычисление
+	if tokens, err := model.Tokenize(currentDisplay); err == nil && model.HasNameAfterParen(tokens) {
+		p.setDisplay("error")
+		return
 	}
 
 	if strings.Contains(currentDisplay, "pi") {
 		index := strings.Index(currentDisplay, "pi")
 		if (index > 0 && unicode.IsDigit(rune(currentDisplay[index-1]))) ||
 			(index+2 < len(currentDisplay) && unicode.IsDigit(rune(currentDisplay[index+2]))) {
-			p.view.UpdatedisplayLabelWithText("error")
+			p.setDisplay("error")
 			return
 		}
 	}
 
-	currentDisplay = strings.ReplaceAll(currentDisplay, "pi", piValue)
-	currentDisplay = helpers.ReplaceEConstant(currentDisplay)
+	exactExpression := currentDisplay
+	currentDisplay = expandConstants(currentDisplay)
 
 	if !helpers.IsValidInput(currentDisplay) {
 		currentDisplay = "0"
+		exactExpression = "0"
 	}
 
-	if currentDisplay != "0" {
-		p.SaveHistory()
+	// В историю попадает исходное выражение вместе с результатом на дисплее;
+	// выражения, которые не удалось вычислить, не сохраняются
+	expression := p.state.Display
+	record := currentDisplay != "0"
+	saveResult := func() {
+		if record {
+			p.saveHistory(history.Entry{Kind: history.KindExpression, Expression: expression, Result: p.state.Display})
+		}
 	}
 
-	p.view.UpdatedisplayLabelWithText(p.calculateResult(&currentDisplay, p.view.GetVariableXLabel()))
+	p.setStatus("")
+	if p.useExact {
+		exactExpression, err := p.prepareExpression(exactExpression)
+		if err != nil {
+			p.setDisplay(err.Error())
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), evaluationTimeout)
+		res, err := p.model.CalculateExact(ctx, exactExpression, p.state.X)
+		cancel()
+		switch {
+		case err == nil:
+			value, _ := res.Float64()
+			p.rememberAnswer(value)
+			p.setDisplay(p.formatExactResult(res))
+			saveResult()
+			return
+		case !errors.Is(err, model.ErrNotExact):
+			p.setDisplay(err.Error())
+			return
+		}
+		p.setStatus(floatFallbackStatus)
+	}
+
+	result, err := p.calculateResult(&currentDisplay, p.state.X, true)
+	p.setDisplay(result)
+	if err == nil {
+		saveResult()
+	}
 }
@@ -0,0 +1,83 @@
+package presenter
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/helpers"
+)
+
+const previewDelay = 250 * time.Millisecond
+
+// Preview — результат выражения, вычисленный во время ввода, без нажатия =.
+func (p *Presenter) Preview() string {
+	p.previewMu.Lock()
+	defer p.previewMu.Unlock()
+	return p.preview
+}
+
+// schedulePreview откладывает вычисление на previewDelay: пока пользователь
+// печатает, ядро не вызывается. В историю и ans предпросмотр не попадает.
+// Состояние и режимы формата читаются здесь, до запуска таймера: интерфейс
+// меняет их, пока предпросмотр считается в фоне.
+//
+// Уже начатое вычисление не отменяется: отмена перезапускает процесс ядра,
+// а в режиме inprocess занимает ядро до конца брошенного вызова. Результат
+// устаревшего вычисления отбрасывает setPreview по номеру.
+func (p *Presenter) schedulePreview() {
+	display := p.state.Display
+	x := p.state.X
+	fraction, scientific := p.showFraction, p.useScientific
+
+	p.previewMu.Lock()
+	p.previewGeneration++
+	generation := p.previewGeneration
+	if p.previewTimer != nil {
+		p.previewTimer.Stop()
+	}
+	p.previewMu.Unlock()
+
+	// У одиночного числа и сообщения об ошибке предпросмотра нет
+	if _, err := strconv.ParseFloat(display, 64); err == nil || !helpers.IsValidInput(display) {
+		p.setPreview(generation, "")
+		return
+	}
+
+	prepared, err := p.prepareExpression(expandConstants(display))
+	if err != nil {
+		return
+	}
+
+	p.previewMu.Lock()
+	defer p.previewMu.Unlock()
+	p.previewTimer = time.AfterFunc(previewDelay, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), evaluationTimeout)
+		defer cancel()
+		res, err := p.model.CalculateContext(ctx, prepared, xVars(x))
+		if err != nil {
+			// Незаконченное выражение: остается последнее верное значение
+			return
+		}
+		p.setPreview(generation, formatValue(res, fraction, scientific))
+	})
+}
+
+// setPreview сохраняет предпросмотр под previewMu, а интерфейс вызывает уже
+// без него. previewRenderMu упорядочивает отрисовку: последним на экран
+// попадает текущее значение, а не то, чья горутина опоздала.
+func (p *Presenter) setPreview(generation uint64, text string) {
+	p.previewMu.Lock()
+	changed := generation == p.previewGeneration && text != p.preview
+	if changed {
+		p.preview = text
+	}
+	p.previewMu.Unlock()
+
+	if !changed || p.view == nil {
+		return
+	}
+	p.previewRenderMu.Lock()
+	defer p.previewRenderMu.Unlock()
+	p.view.RenderPreview(p.Preview())
+}
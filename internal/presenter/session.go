@@ -0,0 +1,272 @@
+package presenter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/helpers"
+)
+
+const (
+	DefaultSession  = "default"
+	lastSessionFile = "last_session"
+	sessionFileExt  = ".json"
+)
+
+// Вспомогательные окна, открытие которых запоминается в сеансе
+const (
+	WindowPlot      = "plot"
+	WindowCredit    = "credit"
+	WindowHistory   = "history"
+	WindowHelp      = "help"
+	WindowFunctions = "functions"
+	WindowMemory    = "memory"
+)
+
+var (
+	sessionNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	ErrNoSessions      = errors.New("session directory is not set")
+)
+
+// Session — рабочее состояние, которое сохраняется при выходе: строка ввода,
+// x, режимы Exact и Fraction, открытые окна и последние параметры графика
+// и кредитного калькулятора.
+type Session struct {
+	Display  string       `json:"display"`
+	Cursor   int          `json:"cursor"`
+	X        string       `json:"x"`
+	Exact    bool         `json:"exact,omitempty"`
+	Fraction bool         `json:"fraction,omitempty"`
+	Windows  []string     `json:"windows,omitempty"`
+	Plot     PlotRange    `json:"plot"`
+	Credit   CreditInputs `json:"credit"`
+}
+
+type PlotRange struct {
+	XMin float64 `json:"x_min"`
+	XMax float64 `json:"x_max"`
+	YMin float64 `json:"y_min"`
+	YMax float64 `json:"y_max"`
+}
+
+var defaultPlotRange = PlotRange{XMin: -10, XMax: 10, YMin: -10, YMax: 10}
+
+// CreditInputs — поля кредитного калькулятора в том виде, как их ввел пользователь.
+type CreditInputs struct {
+	Amount string `json:"amount,omitempty"`
+	Term   string `json:"term,omitempty"`
+	Rate   string `json:"rate,omitempty"`
+	Type   string `json:"type,omitempty"`
+}
+
+// workspace — часть сеанса, которую заполняет интерфейс.
+type workspace struct {
+	windows map[string]bool
+	plot    PlotRange
+	credit  CreditInputs
+}
+
+func newWorkspace() workspace {
+	return workspace{windows: map[string]bool{}, plot: defaultPlotRange}
+}
+
+func (p *Presenter) SessionName() string {
+	return p.sessionName
+}
+
+func (p *Presenter) SetWindowOpen(name string, open bool) {
+	if open {
+		p.workspace.windows[name] = true
+	} else {
+		delete(p.workspace.windows, name)
+	}
+}
+
+func (p *Presenter) PlotRange() PlotRange {
+	return p.workspace.plot
+}
+
+func (p *Presenter) SetPlotRange(r PlotRange) {
+	p.workspace.plot = r
+}
+
+func (p *Presenter) CreditInputs() CreditInputs {
+	return p.workspace.credit
+}
+
+func (p *Presenter) SetCreditInputs(inputs CreditInputs) {
+	p.workspace.credit = inputs
+}
+
+// CurrentSession собирает сеанс из состояния презентера.
+func (p *Presenter) CurrentSession() Session {
+	windows := make([]string, 0, len(p.workspace.windows))
+	for name := range p.workspace.windows {
+		windows = append(windows, name)
+	}
+	sort.Strings(windows)
+
+	return Session{
+		Display:  p.state.Display,
+		Cursor:   p.state.Cursor,
+		X:        p.state.X,
+		Exact:    p.useExact,
+		Fraction: p.showFraction,
+		Windows:  windows,
+		Plot:     p.workspace.plot,
+		Credit:   p.workspace.credit,
+	}
+}
+
+// SaveSession сохраняет текущий сеанс под его именем.
+func (p *Presenter) SaveSession() error {
+	return p.SaveSessionAs(p.sessionName)
+}
+
+// SaveSessionAs сохраняет текущий сеанс под новым именем и переключается на него.
+func (p *Presenter) SaveSessionAs(name string) error {
+	path, err := p.sessionPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(p.config.SessionDir, 0755); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(p.CurrentSession(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return err
+	}
+	p.sessionName = name
+	p.rememberSessionName()
+	return nil
+}
+
+// LoadSession переключается на сохраненный сеанс и возвращает его, чтобы
+// интерфейс открыл окна сеанса. Правки прежнего сеанса не отменяются через Undo.
+func (p *Presenter) LoadSession(name string) (Session, error) {
+	path, err := p.sessionPath(name)
+	if err != nil {
+		return Session{}, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Session{}, err
+	}
+	var session Session
+	if err := json.Unmarshal(content, &session); err != nil {
+		return Session{}, fmt.Errorf("invalid session %q: %w", name, err)
+	}
+
+	p.applySession(session)
+	p.sessionName = name
+	p.rememberSessionName()
+	return p.CurrentSession(), nil
+}
+
+// RestoreSession загружает сеанс, с которым программа работала в прошлый раз.
+// Если сохраненного сеанса нет, остается пустой сеанс по умолчанию.
+func (p *Presenter) RestoreSession() (Session, error) {
+	if p.config.SessionDir == "" {
+		return p.CurrentSession(), nil
+	}
+	name := DefaultSession
+	if content, err := os.ReadFile(filepath.Join(p.config.SessionDir, lastSessionFile)); err == nil {
+		if last := strings.TrimSpace(string(content)); sessionNamePattern.MatchString(last) {
+			name = last
+		}
+	}
+
+	session, err := p.LoadSession(name)
+	if errors.Is(err, os.ErrNotExist) {
+		p.sessionName = name
+		return p.CurrentSession(), nil
+	}
+	return session, err
+}
+
+// SessionNames возвращает имена сохраненных сеансов по алфавиту.
+func (p *Presenter) SessionNames() ([]string, error) {
+	if p.config.SessionDir == "" {
+		return nil, ErrNoSessions
+	}
+	files, err := os.ReadDir(p.config.SessionDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, file := range files {
+		name, ok := strings.CutSuffix(file.Name(), sessionFileExt)
+		if ok && !file.IsDir() && sessionNamePattern.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// DeleteSession удаляет сохраненный сеанс; текущий сеанс удалить нельзя.
+func (p *Presenter) DeleteSession(name string) error {
+	if name == p.sessionName {
+		return fmt.Errorf("session %q is in use", name)
+	}
+	path, err := p.sessionPath(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (p *Presenter) applySession(session Session) {
+	if !helpers.IsValidInput(session.Display) || session.Display == "" {
+		session.Display = "0"
+	}
+	if session.X == "" {
+		session.X = "0"
+	}
+	session.Cursor = max(0, min(session.Cursor, len(session.Display)))
+
+	p.useExact = session.Exact
+	p.showFraction = session.Fraction
+	p.workspace = newWorkspace()
+	for _, name := range session.Windows {
+		p.workspace.windows[name] = true
+	}
+	if session.Plot != (PlotRange{}) {
+		p.workspace.plot = session.Plot
+	}
+	p.workspace.credit = session.Credit
+
+	p.undo, p.redo = nil, nil
+	p.restoreEditorState(editorState{Display: session.Display, Cursor: session.Cursor, X: session.X})
+}
+
+func (p *Presenter) sessionPath(name string) (string, error) {
+	if p.config.SessionDir == "" {
+		return "", ErrNoSessions
+	}
+	if !sessionNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid session name %q: use letters, digits, '-' and '_'", name)
+	}
+	return filepath.Join(p.config.SessionDir, name+sessionFileExt), nil
+}
+
+func (p *Presenter) rememberSessionName() {
+	path := filepath.Join(p.config.SessionDir, lastSessionFile)
+	if err := os.WriteFile(path, []byte(p.sessionName+"\n"), 0644); err != nil {
+		log.Printf("Failed to remember session '%s': %v", p.sessionName, err)
+	}
+}
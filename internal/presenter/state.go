@@ -0,0 +1,171 @@
+package presenter
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/helpers"
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/history"
+)
+
+const maxDisplayLength = 256
+
+// Config — окружение презентера: хранилище истории, файл памяти, каталог
+// сеансов, ограничения истории, режим клавиши % и привязки клавиш (nil —
+// привязки по умолчанию).
+// HistoryStore, если задано, используется вместо HistoryBackend и HistoryFilePath;
+// HistorySkipIntermediate отключает записи от +/- и построения графика.
+type Config struct {
+	HistoryStore            history.Store
+	HistoryBackend          string
+	HistoryFilePath         string
+	HistoryRetention        history.Retention
+	HistorySkipIntermediate bool
+	MemoryFilePath          string
+	SessionDir              string
+	PercentMode             string
+	KeyBindings             KeyBindings
+}
+
+// ConfigFromEnv читает настройки из переменных окружения (.env).
+func ConfigFromEnv() Config {
+	keyBindings, err := LoadKeyBindings(os.Getenv("KEY_BINDINGS_FILE_PATH"))
+	if err != nil {
+		log.Printf("Failed to load key bindings: %v", err)
+	}
+	return Config{
+		HistoryBackend:          os.Getenv("HISTORY_BACKEND"),
+		HistoryFilePath:         os.Getenv("HISTORY_FILE_PATH"),
+		HistoryRetention:        historyRetentionFromEnv(),
+		HistorySkipIntermediate: os.Getenv("HISTORY_SKIP_INTERMEDIATE") == "true",
+		MemoryFilePath:          memoryFilePathFromEnv(),
+		SessionDir:              os.Getenv("SESSION_DIR"),
+		PercentMode:             os.Getenv("PERCENT_MODE"),
+		KeyBindings:             keyBindings,
+	}
+}
+
+// Без MEMORY_FILE_PATH память хранится в каталоге настроек пользователя,
+// чтобы ячейки и ans все равно переживали перезапуск.
+func memoryFilePathFromEnv() string {
+	if path := os.Getenv("MEMORY_FILE_PATH"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		log.Printf("Memory is not saved between runs: %v", err)
+		return ""
+	}
+	return filepath.Join(dir, "smartcalc", "memory.json")
+}
+
+// HISTORY_MAX_ENTRIES — число записей, HISTORY_MAX_AGE — срок (30d, 720h);
+// неверные значения не ограничивают историю.
+func historyRetentionFromEnv() history.Retention {
+	var retention history.Retention
+	if value := os.Getenv("HISTORY_MAX_ENTRIES"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			log.Printf("Invalid HISTORY_MAX_ENTRIES '%s', history is not limited by size", value)
+		} else {
+			retention.MaxEntries = n
+		}
+	}
+	if value := os.Getenv("HISTORY_MAX_AGE"); value != "" {
+		age, err := history.ParseAge(value)
+		if err != nil {
+			log.Printf("Invalid HISTORY_MAX_AGE: %v", err)
+		} else {
+			retention.MaxAge = age
+		}
+	}
+	return retention
+}
+
+// State — все, что нужно отрисовать: строка ввода с позицией курсора
+// (смещение в байтах), предпросмотр результата, значение x, строка статуса
+// и доступность Undo/Redo.
+type State struct {
+	Display string
+	Cursor  int
+	Preview string
+	X       string
+	Status  string
+	CanUndo bool
+	CanRedo bool
+}
+
+func (p *Presenter) State() State {
+	state := p.state
+	state.Preview = p.Preview()
+	state.CanUndo = p.CanUndo()
+	state.CanRedo = p.CanRedo()
+	return state
+}
+
+func (p *Presenter) Display() string {
+	return p.state.Display
+}
+
+func (p *Presenter) X() string {
+	return p.state.X
+}
+
+func (p *Presenter) Status() string {
+	return p.state.Status
+}
+
+func (p *Presenter) SetUseScientific(enabled bool) {
+	p.useScientific = enabled
+}
+
+func (p *Presenter) SetUseExact(enabled bool) {
+	p.useExact = enabled
+}
+
+func (p *Presenter) SetShowFraction(enabled bool) {
+	p.showFraction = enabled
+}
+
+// LoadExpression заменяет строку ввода, например выражением из истории.
+func (p *Presenter) LoadExpression(expression string) {
+	defer p.recordEdit()()
+	p.setDisplay(expression)
+}
+
+func (p *Presenter) setDisplay(text string) {
+	p.state.Display = text
+	p.state.Cursor = len(text)
+	p.render()
+}
+
+// setX принимает результат кнопки x<-: число становится значением x,
+// а сообщение об ошибке выводится на дисплей.
+func (p *Presenter) setX(text string) {
+	if !helpers.IsValidInput(text) {
+		p.setDisplay(text)
+		return
+	}
+	p.state.X = text
+	p.setDisplay("0")
+}
+
+func (p *Presenter) setStatus(text string) {
+	p.state.Status = text
+	p.render()
+}
+
+// Без view (nil) презентер работает без интерфейса: CLI, сервер, тесты
+func (p *Presenter) render() {
+	if p.view != nil {
+		p.view.Render(p.State())
+	}
+}
+
+func (p *Presenter) refreshEngineFeatures() {
+	if p.view != nil {
+		p.view.RefreshEngineFeatures()
+	}
+}
@@ -0,0 +1,107 @@
+package presenter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const maxTableValues = 10000
+
+// XTable — значения нескольких выражений для списка x:
+// Values[i][j] — выражение Expressions[j] при X[i].
+type XTable struct {
+	Expressions []string
+	X           []float64
+	Values      [][]string
+}
+
+// ParseXValues разбирает список значений x: числа через запятую, точку
+// с запятой или пробел и диапазоны from..to или from..to:step (шаг 1).
+// Десятичный разделитель — точка.
+func ParseXValues(text string) ([]float64, error) {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\n'
+	})
+	if len(fields) == 0 {
+		return nil, errors.New("enter at least one value of x")
+	}
+
+	var values []float64
+	for _, field := range fields {
+		from, to, isRange := strings.Cut(field, "..")
+		if !isRange {
+			x, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value of x %q", field)
+			}
+			values = append(values, x)
+			continue
+		}
+
+		to, stepText, hasStep := strings.Cut(to, ":")
+		start, err1 := strconv.ParseFloat(from, 64)
+		end, err2 := strconv.ParseFloat(to, 64)
+		step := 1.0
+		var err3 error
+		if hasStep {
+			step, err3 = strconv.ParseFloat(stepText, 64)
+		}
+		if err1 != nil || err2 != nil || err3 != nil || !(step > 0) || math.IsInf(end-start, 0) || math.IsNaN(end-start) {
+			return nil, fmt.Errorf("invalid range %q, expected from..to:step with a positive step", field)
+		}
+
+		// Число шагов проверяется до перевода в int: у диапазонов вроде
+		// 1..1e300:1e-300 оно не помещается ни в один целый тип
+		steps := math.Floor(math.Abs(end-start)/step + 1e-9)
+		if float64(len(values))+steps+1 > maxTableValues {
+			return nil, fmt.Errorf("range %q has too many values of x, at most %d", field, maxTableValues)
+		}
+		// Значения считаются от начала диапазона, чтобы не копить ошибку шага
+		count := int(steps) + 1
+		direction := 1.0
+		if end < start {
+			direction = -1
+		}
+		for i := 0; i < count; i++ {
+			values = append(values, start+direction*float64(i)*step)
+		}
+	}
+	if len(values) > maxTableValues {
+		return nil, fmt.Errorf("too many values of x, at most %d", maxTableValues)
+	}
+	return values, nil
+}
+
+// EvaluateTable вычисляет выражения (например, записи истории) для каждого
+// значения x. Ошибка вычисления отдельной ячейки попадает в таблицу как Error.
+func (p *Presenter) EvaluateTable(ctx context.Context, expressions []string, xs []float64) (XTable, error) {
+	if len(expressions) == 0 {
+		return XTable{}, errors.New("select at least one expression")
+	}
+	if err := p.CheckIterations(len(expressions) * len(xs)); err != nil {
+		return XTable{}, err
+	}
+
+	table := XTable{Expressions: expressions, X: xs, Values: make([][]string, len(xs))}
+	for i := range table.Values {
+		table.Values[i] = make([]string, len(expressions))
+	}
+	for j, expression := range expressions {
+		ys, errs := p.EvaluateBatch(ctx, expression, xs)
+		if err := ctx.Err(); err != nil {
+			return XTable{}, err
+		}
+		for i := range xs {
+			if errs[i] != nil {
+				table.Values[i][j] = "Error"
+			} else {
+				table.Values[i][j] = p.formatResult(ys[i], false)
+			}
+		}
+	}
+	return table, nil
+}
@@ -0,0 +1,86 @@
+package presenter
+
+const maxUndoSteps = 100
+
+// editorState — то, что возвращают Undo и Redo: строка ввода с курсором и x.
+type editorState struct {
+	Display string
+	Cursor  int
+	X       string
+}
+
+func (p *Presenter) editorState() editorState {
+	return editorState{Display: p.state.Display, Cursor: p.state.Cursor, X: p.state.X}
+}
+
+func (s editorState) sameContent(other editorState) bool {
+	return s.Display == other.Display && s.X == other.X
+}
+
+// recordEdit запоминает состояние до правки; вызывается как defer p.recordEdit()().
+// Перемещение курсора без изменения текста в историю правок не попадает.
+func (p *Presenter) recordEdit() func() {
+	before := p.editorState()
+	return func() {
+		if before.sameContent(p.editorState()) {
+			return
+		}
+		p.schedulePreview()
+
+		// Вложенные правки (MR вызывает AppendButtonText) дают одну запись
+		if n := len(p.undo); n > 0 && p.undo[n-1].sameContent(before) {
+			return
+		}
+		hadUndo, hadRedo := p.CanUndo(), p.CanRedo()
+		p.undo = pushEditorState(p.undo, before)
+		p.redo = nil
+		// Текст уже отрисован, обновляем только доступность Undo/Redo
+		if !hadUndo || hadRedo {
+			p.render()
+		}
+	}
+}
+
+func pushEditorState(stack []editorState, state editorState) []editorState {
+	stack = append(stack, state)
+	if len(stack) > maxUndoSteps {
+		stack = stack[len(stack)-maxUndoSteps:]
+	}
+	return stack
+}
+
+func (p *Presenter) CanUndo() bool {
+	return len(p.undo) > 0
+}
+
+func (p *Presenter) CanRedo() bool {
+	return len(p.redo) > 0
+}
+
+func (p *Presenter) Undo() {
+	if len(p.undo) == 0 {
+		return
+	}
+	state := p.undo[len(p.undo)-1]
+	p.undo = p.undo[:len(p.undo)-1]
+	p.redo = pushEditorState(p.redo, p.editorState())
+	p.restoreEditorState(state)
+}
+
+func (p *Presenter) Redo() {
+	if len(p.redo) == 0 {
+		return
+	}
+	state := p.redo[len(p.redo)-1]
+	p.redo = p.redo[:len(p.redo)-1]
+	p.undo = pushEditorState(p.undo, p.editorState())
+	p.restoreEditorState(state)
+}
+
+func (p *Presenter) restoreEditorState(state editorState) {
+	p.state.Display = state.Display
+	p.state.Cursor = state.Cursor
+	p.state.X = state.X
+	p.render()
+	p.schedulePreview()
+}
@@ -4,10 +4,11 @@ import (
 	"fyne.io/fyne/v2/canvas"
 	"image/color"
 	"log"
-	"os"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/joho/godotenv"
 	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/presenter"
@@ -23,19 +24,26 @@ type ButtonConfig struct {
 	Callback func()
 }
 
-type View struct {
-	mainWindow      fyne.Window
-	displayLabel    *widget.Label
-	variableXLabel  *widget.Label
-	variableLabel   *widget.Label
-	presenter       *presenter.Presenter
-	historyFilePath string
-	counter         int
-	useScientific   bool
+type calculatorButton struct {
+	button *widget.Button
+	text   *canvas.Text
 }
 
-func (v *View) GetUseScientific() bool {
-	return v.useScientific
+type View struct {
+	mainWindow     fyne.Window
+	buttons        map[string]calculatorButton
+	displayLabel   *displayLabel
+	variableXLabel *widget.Label
+	variableLabel  *widget.Label
+	previewLabel   *widget.Label
+	statusLabel    *widget.Label
+	undoButton     *widget.Button
+	redoButton     *widget.Button
+	sessionsButton *widget.Button
+	exactCheck     *widget.Check
+	fractionCheck  *widget.Check
+	windows        map[string]fyne.Window
+	presenter      *presenter.Presenter
 }
 
 func init() {
@@ -45,30 +53,39 @@ func init() {
 	}
 }
 
-func (v *View) GetHistoryFilePath() string {
-	return os.Getenv("HISTORY_FILE_PATH")
-}
-
 func NewCalculatorView(myApp fyne.App) *View {
-
-	historyFilePath := os.Getenv("HISTORY_FILE_PATH")
-
 	view := &View{
-		mainWindow:      myApp.NewWindow(WindowTitle),
-		displayLabel:    widget.NewLabel(DefaultNumber),
-		variableXLabel:  widget.NewLabel(DefaultNumber),
-		variableLabel:   widget.NewLabel("x:"),
-		historyFilePath: historyFilePath,
-		counter:         1,
+		mainWindow:     myApp.NewWindow(WindowTitle),
+		displayLabel:   newDisplayLabel(DefaultNumber),
+		variableXLabel: widget.NewLabel(DefaultNumber),
+		variableLabel:  widget.NewLabel("x:"),
+		previewLabel:   widget.NewLabel(""),
+		statusLabel:    widget.NewLabel(""),
+		buttons:        make(map[string]calculatorButton),
+		windows:        make(map[string]fyne.Window),
 	}
 
+	view.undoButton = widget.NewButtonWithIcon("", theme.ContentUndoIcon(), view.undo)
+	view.redoButton = widget.NewButtonWithIcon("", theme.ContentRedoIcon(), view.redo)
+	view.sessionsButton = widget.NewButtonWithIcon("", theme.FolderOpenIcon(), view.openSessions)
+	view.exactCheck = widget.NewCheck("Exact", func(checked bool) {
+		view.presenter.SetUseExact(checked)
+	})
+	view.fractionCheck = widget.NewCheck("Fraction", func(checked bool) {
+		view.presenter.SetShowFraction(checked)
+	})
+
 	view.variableLabel.TextStyle = fyne.TextStyle{Bold: true}
 	view.variableXLabel.TextStyle = fyne.TextStyle{Italic: true}
+	view.statusLabel.TextStyle = fyne.TextStyle{Italic: true}
+	view.previewLabel.Importance = widget.LowImportance
 	view.displayLabel.TextStyle = fyne.TextStyle{Bold: false, Italic: true}
 	view.displayLabel.Alignment = fyne.TextAlignTrailing
+	view.displayLabel.onTapped = view.setCursorFromTap
+	view.displayLabel.onTappedSecondary = view.showClipboardMenu
 
 	view.mainWindow.SetContent(view.createCalculatorLayout())
-	view.mainWindow.Resize(fyne.NewSize(445, 250))
+	view.mainWindow.Resize(fyne.NewSize(445, 335))
 	view.mainWindow.SetFixedSize(true)
 	view.mainWindow.Show()
 
@@ -77,10 +94,42 @@ func NewCalculatorView(myApp fyne.App) *View {
 
 func (v *View) InitPresenter(p *presenter.Presenter) {
 	v.presenter = p
+	v.bindKeys(p.KeyBindings())
+	v.Render(p.State())
+	v.RefreshEngineFeatures()
+}
+
+// RefreshEngineFeatures включает только кнопки, которые поддерживает загруженное ядро.
+func (v *View) RefreshEngineFeatures() {
+	for label, b := range v.buttons {
+		supported := true
+		switch label {
+		case "sqrt", "ln", "log", "sin", "cos", "tan", "asin", "acos", "atan":
+			supported = v.presenter.SupportsFunction(label)
+		case "^", "%", "/", "*", "+", "-":
+			supported = v.presenter.SupportsOperator(label)
+		case "mod":
+			supported = v.presenter.SupportsOperator("%")
+		case "Credit":
+			supported = v.presenter.SupportsCredit()
+		}
+
+		if supported {
+			b.button.Enable()
+			b.text.Color = color.Black
+		} else {
+			b.button.Disable()
+			b.text.Color = color.Gray{Y: 150}
+		}
+		b.text.Refresh()
+	}
 }
 
 func (v *View) createCalculatorLayout() *fyne.Container {
-	variableBox := container.NewHBox(v.variableLabel, v.variableXLabel)
+	variableBox := container.NewHBox(v.variableLabel, v.variableXLabel, v.previewLabel)
+	modeBox := container.NewHBox(v.undoButton, v.redoButton, v.sessionsButton, v.exactCheck, v.fractionCheck, v.statusLabel)
+
+	buttonRow := v.createButtonRow(v.getButtonRowConfig0(), color.NRGBA{R: 185, G: 200, B: 240, A: 128})
 
 	buttonBox := container.NewHBox(
 		v.createButtonColumn(v.getButtonColumnConfig0(), color.NRGBA{R: 220, G: 185, B: 240, A: 128}),
@@ -98,37 +147,65 @@ func (v *View) createCalculatorLayout() *fyne.Container {
 	scrollDisplayLabel := container.NewHScroll(v.displayLabel)
 	scrollDisplayLabel.SetMinSize(fyne.NewSize(350, 40))
 
-	return container.NewVBox(scrollDisplayLabel, scrollVariableBox, buttonBox)
+	return container.NewVBox(scrollDisplayLabel, scrollVariableBox, modeBox, buttonRow, buttonBox)
+}
+
+func (v *View) createButton(config ButtonConfig, bgColor color.Color) fyne.CanvasObject {
+	background := canvas.NewRectangle(bgColor)
+	background.SetMinSize(fyne.NewSize(60, 40))
+
+	clickableButton := widget.NewButton("", config.Callback)
+
+	buttonText := canvas.NewText(config.Label, color.Black)
+	buttonText.Alignment = fyne.TextAlignCenter
+	buttonText.TextStyle = fyne.TextStyle{Bold: true}
+
+	buttonWithBackground := container.NewStack(
+		clickableButton,
+		background,
+		container.NewCenter(buttonText),
+	)
+
+	buttonWithBackground.Resize(fyne.NewSize(60, 40))
+	v.buttons[strings.TrimSpace(config.Label)] = calculatorButton{button: clickableButton, text: buttonText}
+	return buttonWithBackground
 }
 
 func (v *View) createButtonColumn(configs []ButtonConfig, bgColor color.Color) *fyne.Container {
 	var buttons []fyne.CanvasObject
 	for _, config := range configs {
-		background := canvas.NewRectangle(bgColor)
-		background.SetMinSize(fyne.NewSize(60, 40))
+		buttons = append(buttons, v.createButton(config, bgColor))
+	}
 
-		clickableButton := widget.NewButton("", config.Callback)
+	return container.NewVBox(buttons...)
+}
 
-		buttonText := canvas.NewText(config.Label, color.Black)
-		buttonText.Alignment = fyne.TextAlignCenter
-		buttonText.TextStyle = fyne.TextStyle{Bold: true}
+func (v *View) createButtonRow(configs []ButtonConfig, bgColor color.Color) *fyne.Container {
+	var buttons []fyne.CanvasObject
+	for _, config := range configs {
+		buttons = append(buttons, v.createButton(config, bgColor))
+	}
 
-		buttonWithBackground := container.NewStack(
-			clickableButton,
-			background,
-			container.NewCenter(buttonText),
-		)
+	return container.NewGridWithColumns(len(buttons), buttons...)
+}
 
-		buttonWithBackground.Resize(fyne.NewSize(60, 40))
-		buttons = append(buttons, buttonWithBackground)
+func (v *View) getButtonRowConfig0() []ButtonConfig {
+	return []ButtonConfig{
+		{"MC", v.memoryClear},
+		{"MR", v.memoryRecall},
+		{"M+", v.memoryAdd},
+		{"M-", v.memorySubtract},
+		{"MS", v.openMemory},
+		{"Ans", func() { v.appendButtonText("ans") }},
+		{"mod", func() { v.appendOperator("mod") }},
+		{"f(x)", v.openFunctions},
+		{",", func() { v.appendButtonText(",") }},
 	}
-
-	return container.NewVBox(buttons...)
 }
 
 func (v *View) getButtonColumnConfig0() []ButtonConfig {
 	return []ButtonConfig{
-		{"Plot", v.openPlot},
+		{"Plot", v.plot},
 		{" Help ", v.openHelp},
 		{"History", v.openHistory},
 		{"Credit", v.openCreditCalculator},
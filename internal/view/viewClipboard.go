@@ -0,0 +1,53 @@
+package view
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/presenter"
+)
+
+// runClipboardAction выполняет действия с буфером обмена; false — это другое действие.
+func (v *View) runClipboardAction(action string) bool {
+	var text string
+	var err error
+
+	switch action {
+	case presenter.ActionCopyResult:
+		text, err = v.presenter.CopyResult(false)
+	case presenter.ActionCopyResultLaTeX:
+		text, err = v.presenter.CopyResult(true)
+	case presenter.ActionCopyExpression:
+		text, err = v.presenter.CopyExpression(false)
+	case presenter.ActionCopyExpressionLaTeX:
+		text, err = v.presenter.CopyExpression(true)
+	case presenter.ActionPaste:
+		err = v.presenter.Paste(v.mainWindow.Clipboard().Content())
+	default:
+		return false
+	}
+
+	if err != nil {
+		dialog.ShowError(err, v.mainWindow)
+		return true
+	}
+	if action != presenter.ActionPaste {
+		v.mainWindow.Clipboard().SetContent(text)
+	}
+	return true
+}
+
+func (v *View) showClipboardMenu(position fyne.Position) {
+	item := func(label, action string) *fyne.MenuItem {
+		return fyne.NewMenuItem(label, func() { v.runClipboardAction(action) })
+	}
+	menu := fyne.NewMenu("",
+		item("Copy result", presenter.ActionCopyResult),
+		item("Copy expression", presenter.ActionCopyExpression),
+		item("Copy result as LaTeX", presenter.ActionCopyResultLaTeX),
+		item("Copy expression as LaTeX", presenter.ActionCopyExpressionLaTeX),
+		fyne.NewMenuItemSeparator(),
+		item("Paste", presenter.ActionPaste),
+	)
+	widget.ShowPopUpMenuAtPosition(menu, v.mainWindow.Canvas(), position)
+}
@@ -7,11 +7,12 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/widget"
 	"image/color"
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/presenter"
 	"strconv"
 )
 
 func (v *View) openCreditCalculator() {
-	calcWindow := fyne.CurrentApp().NewWindow("Credit Calculator")
+	calcWindow := v.auxWindow(presenter.WindowCredit, "Credit Calculator")
 	v.showCreditCalculator(calcWindow)
 }
 
@@ -31,6 +32,25 @@ func (v *View) showCreditCalculator(mainWindow fyne.Window) {
 	creditType := widget.NewSelect([]string{"Annuity", "Differentiated"}, func(selected string) {
 	})
 
+	// Введенные значения сохраняются в сеансе и возвращаются при следующем открытии
+	inputs := v.presenter.CreditInputs()
+	amountEntry.SetText(inputs.Amount)
+	termEntry.SetText(inputs.Term)
+	rateEntry.SetText(inputs.Rate)
+	creditType.SetSelected(inputs.Type)
+	saveInputs := func() {
+		v.presenter.SetCreditInputs(presenter.CreditInputs{
+			Amount: amountEntry.Text,
+			Term:   termEntry.Text,
+			Rate:   rateEntry.Text,
+			Type:   creditType.Selected,
+		})
+	}
+	amountEntry.OnChanged = func(string) { saveInputs() }
+	termEntry.OnChanged = func(string) { saveInputs() }
+	rateEntry.OnChanged = func(string) { saveInputs() }
+	creditType.OnChanged = func(string) { saveInputs() }
+
 	monthlyPaymentLabel := widget.NewLabel("Monthly Payment: ")
 	overpayLabel := widget.NewLabel("Overpayment: ")
 	totalRepaymentLabel := widget.NewLabel("Total Repayment: ")
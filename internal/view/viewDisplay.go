@@ -0,0 +1,72 @@
+package view
+
+import (
+	"math"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/presenter"
+)
+
+const cursorMarker = "|"
+
+// displayLabel — строка ввода, по которой можно щелкнуть, чтобы поставить курсор;
+// правый щелчок открывает меню буфера обмена.
+type displayLabel struct {
+	widget.Label
+	onTapped          func(offset int)
+	onTappedSecondary func(position fyne.Position)
+}
+
+func newDisplayLabel(text string) *displayLabel {
+	label := &displayLabel{}
+	label.Text = text
+	label.ExtendBaseWidget(label)
+	return label
+}
+
+// Tapped переводит координату щелчка в ближайшую позицию между символами.
+func (l *displayLabel) Tapped(event *fyne.PointEvent) {
+	if l.onTapped == nil {
+		return
+	}
+
+	textWidth := fyne.MeasureText(l.Text, theme.TextSize(), l.TextStyle).Width
+	left := theme.InnerPadding()
+	if l.Alignment == fyne.TextAlignTrailing {
+		left = l.Size().Width - theme.InnerPadding() - textWidth
+	}
+	x := event.Position.X - left
+
+	offset, distance := 0, math.Abs(float64(x))
+	for i := 1; i <= len(l.Text); i++ {
+		width := fyne.MeasureText(l.Text[:i], theme.TextSize(), l.TextStyle).Width
+		if d := math.Abs(float64(x - width)); d < distance {
+			offset, distance = i, d
+		}
+	}
+	l.onTapped(offset)
+}
+
+func (l *displayLabel) TappedSecondary(event *fyne.PointEvent) {
+	if l.onTappedSecondary != nil {
+		l.onTappedSecondary(event.AbsolutePosition)
+	}
+}
+
+// displayText показывает курсор, только если он стоит не в конце строки.
+func displayText(state presenter.State) string {
+	if state.Cursor >= len(state.Display) {
+		return state.Display
+	}
+	return state.Display[:state.Cursor] + cursorMarker + state.Display[state.Cursor:]
+}
+
+func (v *View) setCursorFromTap(offset int) {
+	state := v.presenter.State()
+	if state.Cursor < len(state.Display) && offset > state.Cursor {
+		offset -= len(cursorMarker)
+	}
+	v.presenter.SetCursor(offset)
+}
@@ -0,0 +1,70 @@
+package view
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/presenter"
+)
+
+func (v *View) openFunctions() {
+	functionsWindow := v.auxWindow(presenter.WindowFunctions, "Functions")
+	v.showFunctions(functionsWindow)
+}
+
+// showFunctions — список функций с подсказками: ввод сужает список по началу имени,
+// выбор вставляет вызов функции в выражение.
+func (v *View) showFunctions(mainWindow fyne.Window) {
+	functions := v.presenter.FunctionCatalog("")
+
+	functionList := widget.NewList(
+		func() int {
+			return len(functions)
+		},
+		func() fyne.CanvasObject {
+			return container.NewVBox(widget.NewLabel(""), widget.NewLabel(""))
+		},
+		func(index widget.ListItemID, obj fyne.CanvasObject) {
+			info := functions[index]
+			row := obj.(*fyne.Container)
+
+			title := row.Objects[0].(*widget.Label)
+			title.TextStyle = fyne.TextStyle{Bold: true}
+			title.SetText(info.Signature)
+
+			description := info.Doc
+			if info.Source != "" {
+				description += " [" + info.Source + "]"
+			}
+			row.Objects[1].(*widget.Label).SetText(description)
+		},
+	)
+	functionList.OnSelected = func(index widget.ListItemID) {
+		v.insertFunction(functions[index])
+		v.closeAuxWindow(presenter.WindowFunctions, mainWindow)
+	}
+
+	searchEntry := widget.NewEntry()
+	searchEntry.SetPlaceHolder("Start typing a function name")
+	searchEntry.OnChanged = func(prefix string) {
+		functions = v.presenter.FunctionCatalog(prefix)
+		functionList.UnselectAll()
+		functionList.Refresh()
+	}
+	searchEntry.OnSubmitted = func(string) {
+		if len(functions) > 0 {
+			v.insertFunction(functions[0])
+			v.closeAuxWindow(presenter.WindowFunctions, mainWindow)
+		}
+	}
+
+	mainWindow.SetContent(container.NewBorder(searchEntry, nil, nil, nil, functionList))
+	mainWindow.Resize(fyne.NewSize(400, 400))
+	mainWindow.Canvas().Focus(searchEntry)
+	mainWindow.Show()
+}
+
+func (v *View) insertFunction(info presenter.FunctionInfo) {
+	v.appendButtonText(info.Name + "(")
+}
@@ -4,14 +4,16 @@ import (
 	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 	"github.com/joho/godotenv"
 	"io/ioutil"
 	"os"
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/presenter"
 )
 
 func (v *View) openHelp() {
-	helpWindow := fyne.CurrentApp().NewWindow("Help")
+	helpWindow := v.auxWindow(presenter.WindowHelp, "Help")
 	v.showHelp(helpWindow)
 }
 
@@ -20,27 +22,37 @@ func (v *View) showHelp(mainWindow fyne.Window) {
 	err := godotenv.Load(".env")
 	if err != nil {
 		showErrorHelp(fyne.CurrentApp().NewWindow("Error"), "Error loading .env file")
+		v.closeAuxWindow(presenter.WindowHelp, mainWindow)
 		return
 	}
 
 	helpFilePath := os.Getenv("HELP_FILE_PATH")
 	if helpFilePath == "" {
 		showErrorHelp(fyne.CurrentApp().NewWindow("Error"), "HELP_FILE_PATH is not set in .env file")
+		v.closeAuxWindow(presenter.WindowHelp, mainWindow)
 		return
 	}
 
 	content, err := ioutil.ReadFile(helpFilePath)
 	if err != nil {
 		showErrorHelp(fyne.CurrentApp().NewWindow("Error"), fmt.Sprintf("Error reading help file: %v", err))
+		v.closeAuxWindow(presenter.WindowHelp, mainWindow)
 		return
 	}
 
 	helpText := string(content)
+	if extensionsHelp := v.presenter.ExtensionsHelp(); extensionsHelp != "" {
+		helpText += "\n" + extensionsHelp
+	}
 	markdownText := widget.NewRichTextFromMarkdown(helpText)
 
 	scrollContainer := container.NewScroll(markdownText)
 
-	mainWindow.SetContent(scrollContainer)
+	aboutButton := widget.NewButton("About", func() {
+		dialog.ShowInformation("About", v.presenter.AboutText(), mainWindow)
+	})
+
+	mainWindow.SetContent(container.NewBorder(container.NewHBox(aboutButton), nil, nil, nil, scrollContainer))
 
 	mainWindow.Resize(fyne.NewSize(800, 700))
 	mainWindow.CenterOnScreen()
@@ -50,6 +62,6 @@ func (v *View) showHelp(mainWindow fyne.Window) {
 func showErrorHelp(mainWindow fyne.Window, message string) {
 	content := container.NewVBox(widget.NewLabel(message))
 
-	dialog := widget.NewPopUp(content, mainWindow.Canvas())
-	dialog.Show()
+	popUp := widget.NewPopUp(content, mainWindow.Canvas())
+	popUp.Show()
 }
@@ -4,57 +4,218 @@ import (
 	"fmt"
 	"fyne.io/fyne/v2/canvas"
 	"image/color"
-	"os"
+	"log"
 	"strings"
+	"sync"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/history"
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/presenter"
 )
 
 func (v *View) openHistory() {
-	historyWindow := fyne.CurrentApp().NewWindow("History")
+	historyWindow := v.auxWindow(presenter.WindowHistory, "History")
 	v.showHistory(historyWindow)
 }
 
+// historyRow — строка списка истории: заголовок дня или запись.
+type historyRow struct {
+	day   string
+	entry history.Entry
+}
+
+var historyKinds = map[string][]history.Kind{
+	"Expressions": {history.KindExpression},
+	"Plots":       {history.KindPlot},
+	"Credits":     {history.KindCredit},
+}
+
+const historyDateLayout = "2006-01-02"
+
 func (v *View) showHistory(mainWindow fyne.Window) {
-	content, err := os.ReadFile(v.historyFilePath)
-	if err != nil {
-		dialog.ShowError(fmt.Errorf("Failed to read history file: %v", err), mainWindow)
-		return
-	}
+	// refresh вызывается и из наблюдателя за файлом истории, поэтому rows
+	// и currentFilter заменяются целиком под mu, а не меняются на месте
+	var rows []historyRow
+	var currentFilter history.Filter
+	var mu sync.Mutex
+	var refresh func()
+	// Записи, отмеченные для пересчета с другими x
+	selected := map[string]history.Entry{}
 
-	historyLines := strings.Fields(string(content))
-	if len(historyLines) == 0 {
-		historyLines = []string{"No history available."}
-	}
+	searchEntry := widget.NewEntry()
+	searchEntry.SetPlaceHolder("Search")
+	regexCheck := widget.NewCheck("Regex", nil)
+	kindSelect := widget.NewSelect([]string{"All", "Expressions", "Plots", "Credits"}, nil)
+	kindSelect.SetSelected("All")
+	fromEntry, toEntry := widget.NewEntry(), widget.NewEntry()
+	fromEntry.SetPlaceHolder("From " + historyDateLayout)
+	toEntry.SetPlaceHolder("To " + historyDateLayout)
+	statusLabel := widget.NewLabel("")
 
 	historyList := widget.NewList(
 		func() int {
-			return len(historyLines)
+			mu.Lock()
+			defer mu.Unlock()
+			return len(rows)
 		},
 		func() fyne.CanvasObject {
-			return widget.NewButton("", nil)
+			selectCheck := widget.NewCheck("", nil)
+			pinButton := widget.NewButtonWithIcon("", theme.RadioButtonIcon(), nil)
+			noteButton := widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), nil)
+			entry := container.NewStack(widget.NewLabel(""), widget.NewButton("", nil))
+			return container.NewBorder(nil, nil, container.NewHBox(selectCheck, pinButton), noteButton, entry)
 		},
 		func(index widget.ListItemID, obj fyne.CanvasObject) {
-			button := obj.(*widget.Button)
-			button.SetText(historyLines[index])
+			mu.Lock()
+			if index >= len(rows) {
+				mu.Unlock()
+				return
+			}
+			row := rows[index]
+			mu.Unlock()
+			objects := obj.(*fyne.Container).Objects
+			entry := objects[0].(*fyne.Container)
+			left := objects[1].(*fyne.Container)
+			selectCheck := left.Objects[0].(*widget.Check)
+			pinButton := left.Objects[1].(*widget.Button)
+			noteButton := objects[2].(*widget.Button)
+			label := entry.Objects[0].(*widget.Label)
+			button := entry.Objects[1].(*widget.Button)
+
+			if row.day != "" {
+				label.TextStyle = fyne.TextStyle{Bold: true}
+				label.SetText(row.day)
+				label.Show()
+				button.Hide()
+				selectCheck.Hide()
+				pinButton.Hide()
+				noteButton.Hide()
+				return
+			}
+			label.Hide()
+			button.Show()
+			button.SetText(historyEntryLabel(row.entry))
 			button.Importance = widget.LowImportance
 
-			if historyLines[index] != "No history available." {
+			// Расчет кредита нельзя вставить в строку ввода
+			if row.entry.Kind != history.KindCredit {
 				button.OnTapped = func() {
-					v.updateDisplayLabelWithHistory(historyLines[index])
-					mainWindow.Close()
+					v.presenter.InsertExpression(row.entry.Expression)
+					v.closeAuxWindow(presenter.WindowHistory, mainWindow)
 				}
+				button.Enable()
 			} else {
 				button.Disable()
 			}
+
+			key := historyEntryKey(row.entry)
+			_, checked := selected[key]
+			selectCheck.Show()
+			selectCheck.OnChanged = nil
+			selectCheck.SetChecked(checked)
+			selectCheck.OnChanged = func(checked bool) {
+				if checked {
+					selected[key] = row.entry
+				} else {
+					delete(selected, key)
+				}
+			}
+			// Кредитный расчет не зависит от x
+			if row.entry.Kind == history.KindCredit {
+				selectCheck.Disable()
+			} else {
+				selectCheck.Enable()
+			}
+
+			pinButton.Show()
+			if row.entry.Pinned {
+				pinButton.SetIcon(theme.RadioButtonCheckedIcon())
+			} else {
+				pinButton.SetIcon(theme.RadioButtonIcon())
+			}
+			pinButton.OnTapped = func() {
+				if err := v.presenter.PinHistoryEntry(row.entry, !row.entry.Pinned); err != nil {
+					dialog.ShowError(err, mainWindow)
+				}
+				refresh()
+			}
+			noteButton.Show()
+			noteButton.OnTapped = func() {
+				v.editHistoryNote(mainWindow, row.entry, refresh)
+			}
 		},
 	)
 
+	refresh = func() {
+		filter, err := historyFilter(searchEntry.Text, regexCheck.Checked, kindSelect.Selected, fromEntry.Text, toEntry.Text)
+		var groups []history.Group
+		var pinned []history.Entry
+		if err == nil {
+			groups, err = v.presenter.SearchHistory(filter)
+		}
+		if err == nil {
+			pinned, err = v.presenter.PinnedHistory(filter)
+		}
+		if err != nil {
+			statusLabel.SetText(err.Error())
+			return
+		}
+
+		// Закрепленные записи — отдельным разделом над днями
+		var newRows []historyRow
+		count := 0
+		if len(pinned) > 0 {
+			newRows = append(newRows, historyRow{day: "Pinned"})
+			for _, entry := range pinned {
+				newRows = append(newRows, historyRow{entry: entry})
+			}
+		}
+		for _, group := range groups {
+			newRows = append(newRows, historyRow{day: group.Day.Format("02.01.2006")})
+			for _, entry := range group.Entries {
+				newRows = append(newRows, historyRow{entry: entry})
+			}
+			count += len(group.Entries)
+		}
+
+		mu.Lock()
+		rows = newRows
+		currentFilter = filter
+		mu.Unlock()
+		if count == 0 {
+			statusLabel.SetText("No history available.")
+		} else {
+			statusLabel.SetText(fmt.Sprintf("Entries: %d", count))
+		}
+		historyList.Refresh()
+	}
+	searchEntry.OnChanged = func(string) { refresh() }
+	regexCheck.OnChanged = func(bool) { refresh() }
+	kindSelect.OnChanged = func(string) { refresh() }
+	fromEntry.OnChanged = func(string) { refresh() }
+	toEntry.OnChanged = func(string) { refresh() }
+	refresh()
+
+	// Записи других запущенных калькуляторов появляются без переоткрытия окна
+	if stop, err := v.presenter.WatchHistory(refresh); err != nil {
+		log.Printf("History window will not refresh automatically: %v", err)
+	} else {
+		mainWindow.SetOnClosed(stop)
+	}
+
+	filterBox := container.NewVBox(
+		container.NewBorder(nil, nil, nil, regexCheck, searchEntry),
+		container.NewGridWithColumns(3, kindSelect, fromEntry, toEntry),
+		statusLabel,
+	)
+
 	scrollContainer := container.NewScroll(historyList)
-	scrollContainer.SetMinSize(fyne.NewSize(400, 380))
+	scrollContainer.SetMinSize(fyne.NewSize(440, 330))
 
 	buttonBackgroundColor := color.NRGBA{R: 220, G: 185, B: 240, A: 128}
 
@@ -68,11 +229,11 @@ func (v *View) showHistory(mainWindow fyne.Window) {
 	clearButton := widget.NewButton("", func() {
 		dialog.ShowConfirm("Confirm", "Are you sure you want to clear the history?", func(confirmed bool) {
 			if confirmed {
-				err := os.WriteFile(v.historyFilePath, []byte{}, 0644)
+				err := v.presenter.ClearHistory()
 				if err != nil {
 					dialog.ShowError(fmt.Errorf("Failed to clear history: %v", err), mainWindow)
 				} else {
-					v.showHistory(mainWindow)
+					refresh()
 				}
 			}
 		}, mainWindow)
@@ -84,18 +245,131 @@ func (v *View) showHistory(mainWindow fyne.Window) {
 		container.NewCenter(clearButtonText),
 	)
 
+	// Формат выгрузки выбирается по расширению: .csv, .json, .md или файл истории
+	exportHistory := func(filter history.Filter) {
+		dialog.ShowFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil || writer == nil {
+				return
+			}
+			defer writer.Close()
+			format := history.FormatFromPath(writer.URI().Path())
+			if err := v.presenter.ExportHistory(writer, format, filter); err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to export history: %v", err), mainWindow)
+			}
+		}, mainWindow)
+	}
+	exportButton := widget.NewButton("Export", func() {
+		mu.Lock()
+		filter := currentFilter
+		mu.Unlock()
+		exportHistory(filter)
+	})
+	exportPinnedButton := widget.NewButton("Export Pinned", func() {
+		exportHistory(history.Filter{Pinned: true})
+	})
+	importButton := widget.NewButton("Import", func() {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil || reader == nil {
+				return
+			}
+			reader.Close()
+			added, err := v.presenter.ImportHistory(reader.URI().Path())
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to import history: %v", err), mainWindow)
+				return
+			}
+			refresh()
+			statusLabel.SetText(fmt.Sprintf("Imported entries: %d", added))
+		}, mainWindow)
+	})
+
+	compareButton := widget.NewButton("Compare", func() {
+		var expressions []string
+		seen := map[string]bool{}
+		mu.Lock()
+		shown := rows
+		mu.Unlock()
+		for _, row := range shown {
+			if _, ok := selected[historyEntryKey(row.entry)]; ok && row.day == "" && !seen[row.entry.Expression] {
+				seen[row.entry.Expression] = true
+				expressions = append(expressions, row.entry.Expression)
+			}
+		}
+		if len(expressions) == 0 {
+			dialog.ShowInformation("Compare", "Select one or more entries to re-evaluate.", mainWindow)
+			return
+		}
+		v.openXTable(expressions)
+	})
+
 	contentContainer := container.NewVBox(
+		filterBox,
 		scrollContainer,
+		container.NewGridWithColumns(4, compareButton, exportButton, exportPinnedButton, importButton),
 		clearButtonWithBackground,
 	)
 
 	mainWindow.SetContent(contentContainer)
-	mainWindow.Resize(fyne.NewSize(400, 400))
+	mainWindow.Resize(fyne.NewSize(460, 580))
 	mainWindow.CenterOnScreen()
 	mainWindow.SetFixedSize(true)
 	mainWindow.Show()
 }
 
-func (v *View) updateDisplayLabelWithHistory(historyItem string) {
-	v.displayLabel.SetText(historyItem)
+func historyEntryKey(entry history.Entry) string {
+	return entry.Time.Format(time.RFC3339Nano) + "\x00" + entry.Expression
+}
+
+// editHistoryNote открывает диалог заметки к записи; пустая заметка удаляется.
+func (v *View) editHistoryNote(window fyne.Window, entry history.Entry, done func()) {
+	noteEntry := widget.NewEntry()
+	noteEntry.SetText(entry.Note)
+	noteEntry.SetPlaceHolder("Note")
+	items := []*widget.FormItem{widget.NewFormItem(historyEntryLabel(history.Entry{Kind: entry.Kind, Expression: entry.Expression, Result: entry.Result}), noteEntry)}
+	dialog.ShowForm("Note", "Save", "Cancel", items, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		if err := v.presenter.SetHistoryNote(entry, noteEntry.Text); err != nil {
+			dialog.ShowError(err, window)
+		}
+		done()
+	}, window)
+}
+
+func historyEntryLabel(entry history.Entry) string {
+	label := entry.Expression
+	switch {
+	case entry.Kind == history.KindPlot:
+		label = "Plot: " + entry.Expression
+	case entry.Kind == history.KindCredit:
+		label = "Credit: " + entry.Expression + " → " + entry.Result
+	case entry.Result != "" && entry.Result != entry.Expression:
+		label = entry.Expression + " = " + entry.Result
+	}
+	if entry.Note != "" {
+		label += " — " + entry.Note
+	}
+	return label
+}
+
+// historyFilter собирает фильтр из полей окна; даты задаются включительно.
+func historyFilter(query string, regex bool, kind, from, to string) (history.Filter, error) {
+	filter := history.Filter{Query: query, Regex: regex, Kinds: historyKinds[kind]}
+
+	if from = strings.TrimSpace(from); from != "" {
+		day, err := time.ParseInLocation(historyDateLayout, from, time.Local)
+		if err != nil {
+			return filter, fmt.Errorf("invalid start date %q, expected %s", from, historyDateLayout)
+		}
+		filter.From = day
+	}
+	if to = strings.TrimSpace(to); to != "" {
+		day, err := time.ParseInLocation(historyDateLayout, to, time.Local)
+		if err != nil {
+			return filter, fmt.Errorf("invalid end date %q, expected %s", to, historyDateLayout)
+		}
+		filter.To = day.AddDate(0, 0, 1)
+	}
+	return filter, nil
 }
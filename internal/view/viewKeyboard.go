@@ -0,0 +1,87 @@
+package view
+
+import (
+	"log"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/presenter"
+)
+
+var shortcutModifiers = map[string]fyne.KeyModifier{
+	"Ctrl":  fyne.KeyModifierShortcutDefault,
+	"Shift": fyne.KeyModifierShift,
+	"Alt":   fyne.KeyModifierAlt,
+	"Super": fyne.KeyModifierSuper,
+}
+
+// bindKeys направляет клавиатуру в те же обработчики, что и кнопки.
+func (v *View) bindKeys(bindings presenter.KeyBindings) {
+	canvas := v.mainWindow.Canvas()
+	canvas.SetOnTypedRune(func(r rune) {
+		v.runAction(bindings[string(r)])
+	})
+	canvas.SetOnTypedKey(func(event *fyne.KeyEvent) {
+		// Печатные клавиши приходят еще и как символы через SetOnTypedRune
+		if len(event.Name) > 1 {
+			v.runAction(bindings[string(event.Name)])
+		}
+	})
+
+	for key, action := range bindings {
+		if shortcut, ok := parseShortcut(key); ok {
+			canvas.AddShortcut(shortcut, func(fyne.Shortcut) { v.runAction(action) })
+		}
+	}
+}
+
+func (v *View) runAction(action string) {
+	if action == "" || v.presenter.RunEditorAction(action) || v.runClipboardAction(action) {
+		return
+	}
+	b, ok := v.buttons[action]
+	if !ok {
+		log.Printf("Unknown key binding action %q", action)
+		return
+	}
+	// Отключенная кнопка (ядро не поддерживает функцию) не срабатывает и с клавиатуры
+	if !b.button.Disabled() && b.button.OnTapped != nil {
+		b.button.OnTapped()
+	}
+}
+
+// Сочетания, которые драйвер Fyne передает как стандартные команды, а не как CustomShortcut
+var standardShortcuts = map[string]fyne.Shortcut{
+	"Ctrl+Z":       &fyne.ShortcutUndo{},
+	"Ctrl+Y":       &fyne.ShortcutRedo{},
+	"Ctrl+C":       &fyne.ShortcutCopy{},
+	"Ctrl+Insert":  &fyne.ShortcutCopy{},
+	"Ctrl+V":       &fyne.ShortcutPaste{},
+	"Shift+Insert": &fyne.ShortcutPaste{},
+	"Ctrl+X":       &fyne.ShortcutCut{},
+	"Shift+Delete": &fyne.ShortcutCut{},
+	"Ctrl+A":       &fyne.ShortcutSelectAll{},
+}
+
+// parseShortcut разбирает сочетания вида Ctrl+Shift+Z.
+func parseShortcut(key string) (fyne.Shortcut, bool) {
+	if shortcut, ok := standardShortcuts[key]; ok {
+		return shortcut, true
+	}
+
+	var modifier fyne.KeyModifier
+	for {
+		name, rest, found := strings.Cut(key, "+")
+		m, isModifier := shortcutModifiers[name]
+		if !found || !isModifier || rest == "" {
+			break
+		}
+		modifier |= m
+		key = rest
+	}
+	if modifier == 0 {
+		return nil, false
+	}
+	return &desktop.CustomShortcut{KeyName: fyne.KeyName(strings.ToUpper(key)), Modifier: modifier}, true
+}
@@ -0,0 +1,93 @@
+package view
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/presenter"
+)
+
+func (v *View) openMemory() {
+	memoryWindow := v.auxWindow(presenter.WindowMemory, "Memory")
+	v.showMemory(memoryWindow)
+}
+
+func (v *View) showMemory(mainWindow fyne.Window) {
+	slots := v.presenter.MemorySlots()
+
+	slotList := widget.NewList(
+		func() int {
+			return len(slots)
+		},
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, nil,
+				container.NewHBox(widget.NewButton("Recall", nil), widget.NewButton("Delete", nil)),
+				widget.NewLabel(""),
+			)
+		},
+		func(index widget.ListItemID, obj fyne.CanvasObject) {
+			name := slots[index]
+			value, _ := v.presenter.MemoryValue(name)
+
+			row := obj.(*fyne.Container)
+			row.Objects[0].(*widget.Label).SetText(fmt.Sprintf("%s = %s", name, strconv.FormatFloat(value, 'g', -1, 64)))
+
+			buttons := row.Objects[1].(*fyne.Container)
+			buttons.Objects[0].(*widget.Button).OnTapped = func() {
+				v.presenter.MemoryRecallSlot(name)
+				v.closeAuxWindow(presenter.WindowMemory, mainWindow)
+			}
+			buttons.Objects[1].(*widget.Button).OnTapped = func() {
+				v.presenter.MemoryDelete(name)
+				v.showMemory(mainWindow)
+			}
+		},
+	)
+
+	scrollContainer := container.NewScroll(slotList)
+	scrollContainer.SetMinSize(fyne.NewSize(400, 300))
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("Slot name (e.g. M, rate, load)")
+
+	buttonBackgroundColor := color.NRGBA{R: 220, G: 185, B: 240, A: 128}
+
+	storeButtonText := canvas.NewText("Store current value", color.Black)
+	storeButtonText.Alignment = fyne.TextAlignCenter
+	storeButtonText.TextStyle = fyne.TextStyle{Bold: true}
+
+	storeButtonBackground := canvas.NewRectangle(buttonBackgroundColor)
+	storeButtonBackground.SetMinSize(fyne.NewSize(120, 40))
+
+	storeButton := widget.NewButton("", func() {
+		if err := v.presenter.MemoryStore(nameEntry.Text); err != nil {
+			dialog.ShowError(err, mainWindow)
+			return
+		}
+		v.showMemory(mainWindow)
+	})
+
+	storeButtonWithBackground := container.NewStack(
+		storeButton,
+		storeButtonBackground,
+		container.NewCenter(storeButtonText),
+	)
+
+	contentContainer := container.NewVBox(
+		scrollContainer,
+		nameEntry,
+		storeButtonWithBackground,
+	)
+
+	mainWindow.SetContent(contentContainer)
+	mainWindow.Resize(fyne.NewSize(400, 420))
+	mainWindow.CenterOnScreen()
+	mainWindow.SetFixedSize(true)
+	mainWindow.Show()
+}
@@ -1,27 +1,46 @@
 package view
 
 import (
-	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/helpers"
+	"fyne.io/fyne/v2/widget"
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/presenter"
 )
 
 func (v *View) handleEInput() {
 	v.presenter.HandleEInput()
 }
 
-func (v *View) UpdatedisplayLabelWithText(inputText string) {
-	v.counter = len(inputText)
-	v.displayLabel.SetText(inputText)
+func (v *View) Render(state presenter.State) {
+	v.displayLabel.SetText(displayText(state))
+	v.variableXLabel.SetText(state.X)
+	v.statusLabel.SetText(state.Status)
+	v.RenderPreview(state.Preview)
+	setEnabled(v.undoButton, state.CanUndo)
+	setEnabled(v.redoButton, state.CanRedo)
 }
 
-func (v *View) UpdateXLabelWithText(inputText string) {
-	if !helpers.IsValidInput(inputText) {
-		v.UpdatedisplayLabelWithText(inputText)
+func (v *View) RenderPreview(preview string) {
+	if preview != "" {
+		preview = "= " + preview
+	}
+	v.previewLabel.SetText(preview)
+}
+
+func setEnabled(button *widget.Button, enabled bool) {
+	if enabled {
+		button.Enable()
 	} else {
-		v.variableXLabel.SetText(inputText)
-		v.UpdatedisplayLabelWithText("0")
+		button.Disable()
 	}
 }
 
+func (v *View) undo() {
+	v.presenter.Undo()
+}
+
+func (v *View) redo() {
+	v.presenter.Redo()
+}
+
 func (v *View) appendOperator(operator string) {
 	v.presenter.AppendOperator(operator)
 }
@@ -58,14 +77,18 @@ func (v *View) inverseSign() {
 	v.presenter.InverseSign()
 }
 
-func (v *View) GetCounter() int {
-	return v.counter
+func (v *View) memoryClear() {
+	v.presenter.MemoryClear()
+}
+
+func (v *View) memoryRecall() {
+	v.presenter.MemoryRecall()
 }
 
-func (v *View) GetVariableXLabel() string {
-	return v.variableXLabel.Text
+func (v *View) memoryAdd() {
+	v.presenter.MemoryAdd()
 }
 
-func (v *View) GetDisplayLabel() string {
-	return v.displayLabel.Text
+func (v *View) memorySubtract() {
+	v.presenter.MemorySubtract()
 }
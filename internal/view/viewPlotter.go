@@ -2,12 +2,15 @@ package view
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"image/color"
 	"image/png"
 	"log"
 	"math"
 	"strconv"
+	"sync"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
@@ -21,33 +24,93 @@ import (
 	"gonum.org/v1/plot/vg"
 	"gonum.org/v1/plot/vg/draw"
 	"gonum.org/v1/plot/vg/vgimg"
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/presenter"
 )
 
+// plot — кнопка Plot: график строит пользователь, поэтому он попадает в историю.
+func (v *View) plot() {
+	v.presenter.RecordPlot()
+	v.openPlot()
+}
+
+// openPlot только открывает окно; восстановление сеанса вызывает его без записи в историю.
 func (v *View) openPlot() {
-	plotWindow := fyne.CurrentApp().NewWindow(fmt.Sprintf("Plot: %s", getTruncatedLegendLabel(v.displayLabel.Text)))
+	plotWindow := v.auxWindow(presenter.WindowPlot, fmt.Sprintf("Plot: %s", getTruncatedLegendLabel(v.presenter.Display())))
 	v.showPlot(plotWindow)
 }
 
 func (v *View) showPlot(mainWindow fyne.Window) {
-	if v.displayLabel.Text != "0" {
-		v.presenter.SaveHistory()
-	}
-
+	// Окно открывается с диапазоном, который строился в последний раз
+	plotRange := v.presenter.PlotRange()
 	xMinEntry, xMaxEntry := widget.NewEntry(), widget.NewEntry()
-	xMinEntry.SetText("-10")
-	xMaxEntry.SetText("10")
+	xMinEntry.SetText(strconv.FormatFloat(plotRange.XMin, 'g', -1, 64))
+	xMaxEntry.SetText(strconv.FormatFloat(plotRange.XMax, 'g', -1, 64))
 	yMinEntry, yMaxEntry := widget.NewEntry(), widget.NewEntry()
-	yMinEntry.SetText("-10")
-	yMaxEntry.SetText("10")
+	yMinEntry.SetText(strconv.FormatFloat(plotRange.YMin, 'g', -1, 64))
+	yMaxEntry.SetText(strconv.FormatFloat(plotRange.YMax, 'g', -1, 64))
 
 	setupEntryValidation(xMinEntry)
 	setupEntryValidation(xMaxEntry)
 	setupEntryValidation(yMinEntry)
 	setupEntryValidation(yMaxEntry)
 
-	plotCanvas := v.createPlotCanvas(xMinEntry, xMaxEntry, yMinEntry, yMaxEntry)
+	plotContent := container.New(layout.NewCenterLayout())
+	cancelButton := widget.NewButton("Cancel", nil)
+	cancelButton.Hide()
+
+	var mu sync.Mutex
+	var cancelRender context.CancelFunc
+	generation := 0
+
+	// Построение идет в фоне; новый запуск отменяет предыдущий. Выражение
+	// и диапазон читаются здесь, в потоке интерфейса, пока ввод их не изменил
+	render := func() {
+		plotRange := presenter.PlotRange{
+			XMin: parseFloatFromEntry(xMinEntry, -10),
+			XMax: parseFloatFromEntry(xMaxEntry, 10),
+			YMin: parseFloatFromEntry(yMinEntry, -10),
+			YMax: parseFloatFromEntry(yMaxEntry, 10),
+		}
+		v.presenter.SetPlotRange(plotRange)
+		expression := v.presenter.Display()
 
-	plotContent := container.New(layout.NewCenterLayout(), plotCanvas)
+		mu.Lock()
+		if cancelRender != nil {
+			cancelRender()
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancelRender = cancel
+		generation++
+		current := generation
+		mu.Unlock()
+
+		plotContent.Objects = []fyne.CanvasObject{widget.NewLabel("Calculating...")}
+		plotContent.Refresh()
+		cancelButton.OnTapped = cancel
+		cancelButton.Show()
+
+		go func() {
+			newCanvas := v.createPlotCanvas(ctx, expression, plotRange)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if current != generation {
+				return
+			}
+			cancel()
+			plotContent.Objects = []fyne.CanvasObject{newCanvas}
+			plotContent.Refresh()
+			cancelButton.Hide()
+		}()
+	}
+	mainWindow.SetOnClosed(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if cancelRender != nil {
+			cancelRender()
+		}
+	})
+	render()
 
 	buttonBackgroundColor := color.NRGBA{R: 220, G: 185, B: 240, A: 128}
 
@@ -67,9 +130,7 @@ func (v *View) showPlot(mainWindow fyne.Window) {
 			return
 		}
 
-		newCanvas := v.createPlotCanvas(xMinEntry, xMaxEntry, yMinEntry, yMaxEntry)
-		plotContent.Objects = []fyne.CanvasObject{newCanvas}
-		mainWindow.Canvas().Refresh(plotContent)
+		render()
 	})
 
 	buttonWithBackground := container.NewStack(
@@ -82,6 +143,7 @@ func (v *View) showPlot(mainWindow fyne.Window) {
 		container.NewHBox(widget.NewLabel("xMin:"), xMinEntry, widget.NewLabel("xMax:"), xMaxEntry),
 		container.NewHBox(widget.NewLabel("yMin:"), yMinEntry, widget.NewLabel("yMax:"), yMaxEntry),
 		buttonWithBackground,
+		cancelButton,
 	)
 
 	verticalLayout := container.NewVBox(plotContent, horizontalLayout)
@@ -92,31 +154,33 @@ func (v *View) showPlot(mainWindow fyne.Window) {
 	mainWindow.Show()
 }
 
-func (v *View) createPlotCanvas(xMinEntry, xMaxEntry, yMinEntry, yMaxEntry *widget.Entry) fyne.CanvasObject {
+func (v *View) createPlotCanvas(ctx context.Context, expression string, plotRange presenter.PlotRange) fyne.CanvasObject {
 	p := plot.New()
 	p.Title.Text = "Plot"
 	p.X.Label.Text = "X"
 	p.Y.Label.Text = "Y"
 
-	minValueX := parseFloatFromEntry(xMinEntry, -10)
-	maxValueX := parseFloatFromEntry(xMaxEntry, 10)
-	minValueY := parseFloatFromEntry(yMinEntry, -10)
-	maxValueY := parseFloatFromEntry(yMaxEntry, 10)
-
-	p.X.Min = math.Max(minValueX, -1000000)
-	p.X.Max = math.Min(maxValueX, 1000000)
-	p.Y.Min = math.Max(minValueY, -1000000)
-	p.Y.Max = math.Min(maxValueY, 1000000)
+	p.X.Min = math.Max(plotRange.XMin, -1000000)
+	p.X.Max = math.Min(plotRange.XMax, 1000000)
+	p.Y.Min = math.Max(plotRange.YMin, -1000000)
+	p.Y.Max = math.Min(plotRange.YMax, 1000000)
 
 	p.X.Tick.Marker = createAdaptiveTicks(p.X.Min, p.X.Max)
 	p.Y.Tick.Marker = createAdaptiveTicks(p.Y.Min, p.Y.Max)
 
 	p.Add(plotter.NewGrid())
 
-	points := v.generatePlotPoints(minValueX, maxValueX)
+	points, err := v.generatePlotPoints(ctx, expression, plotRange.XMin, plotRange.XMax)
+	if errors.Is(err, context.Canceled) {
+		return widget.NewLabel("Plot cancelled")
+	}
+	if err != nil {
+		log.Printf("Failed to calculate plot: %v", err)
+		return widget.NewLabel("Error: " + err.Error())
+	}
 	validPoints := filterValidPlotPoints(points)
 
-	err := plotutil.AddLinePoints(p, getTruncatedLegendLabel(v.displayLabel.Text), validPoints)
+	err = plotutil.AddLinePoints(p, getTruncatedLegendLabel(expression), validPoints)
 	if err != nil {
 		log.Printf("Failed to plot data: %v", err)
 		return widget.NewLabel("Error: Unable to plot data")
@@ -204,33 +268,35 @@ func filterValidPlotPoints(points plotter.XYs) plotter.XYs {
 	return validPoints
 }
 
-func (v *View) generatePlotPoints(n float64, m float64) plotter.XYs {
-	len := 1000
-	pts := make(plotter.XYs, len)
-	interval := (m - n) / float64(len)
+// Число точек графика; лимит итераций модели проверяет EvaluateBatch
+const plotPoints = 1000
+
+func (v *View) generatePlotPoints(ctx context.Context, expression string, n float64, m float64) (plotter.XYs, error) {
+	pts := make(plotter.XYs, plotPoints)
+	xs := make([]float64, plotPoints)
+	interval := (m - n) / float64(plotPoints)
 
 	currentX := n
-	for i := 0; i < len; i++ {
+	for i := 0; i < plotPoints; i++ {
 		currentX = math.RoundToEven(currentX*1e6) / 1e6
+		xs[i] = currentX
+		currentX += interval
+	}
 
-		pts[i].X = currentX
-		s := v.displayLabel.Text
-
-		yStr, err := v.presenter.CalculatePlotResult(&s, fmt.Sprintf("%f", currentX))
-		if err != nil {
+	ys, errs := v.presenter.EvaluateBatch(ctx, expression, xs)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for i := range pts {
+		pts[i].X = xs[i]
+		if errs[i] != nil || math.IsInf(ys[i], 0) {
 			pts[i].Y = math.NaN()
 		} else {
-			y, convErr := strconv.ParseFloat(yStr, 64)
-			if convErr != nil {
-				pts[i].Y = math.NaN()
-			} else {
-				pts[i].Y = y
-			}
+			pts[i].Y = ys[i]
 		}
-		currentX += interval
 	}
 
-	return pts
+	return pts, nil
 }
 
 func plotToCanvas(p *plot.Plot) (fyne.CanvasObject, error) {
@@ -0,0 +1,150 @@
+package view
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/presenter"
+)
+
+// auxWindow создает вспомогательное окно и отмечает его в сеансе открытым,
+// пока пользователь его не закроет.
+func (v *View) auxWindow(name, title string) fyne.Window {
+	window := fyne.CurrentApp().NewWindow(title)
+	v.windows[name] = window
+	v.presenter.SetWindowOpen(name, true)
+	window.SetCloseIntercept(func() {
+		v.closeAuxWindow(name, window)
+	})
+	return window
+}
+
+func (v *View) closeAuxWindow(name string, window fyne.Window) {
+	if v.windows[name] == window {
+		delete(v.windows, name)
+		v.presenter.SetWindowOpen(name, false)
+	}
+	window.Close()
+}
+
+// RestoreSession восстанавливает сеанс прошлого запуска вместе с его окнами.
+func (v *View) RestoreSession() {
+	session, err := v.presenter.RestoreSession()
+	if err != nil {
+		log.Printf("Failed to restore session: %v", err)
+		return
+	}
+	v.applySession(session)
+}
+
+// SaveSession сохраняет сеанс при выходе из программы.
+func (v *View) SaveSession() {
+	if err := v.presenter.SaveSession(); err != nil && !errors.Is(err, presenter.ErrNoSessions) {
+		log.Printf("Failed to save session: %v", err)
+	}
+}
+
+func (v *View) applySession(session presenter.Session) {
+	v.exactCheck.SetChecked(session.Exact)
+	v.fractionCheck.SetChecked(session.Fraction)
+
+	open := map[string]func(){
+		presenter.WindowPlot:      v.openPlot,
+		presenter.WindowCredit:    v.openCreditCalculator,
+		presenter.WindowHistory:   v.openHistory,
+		presenter.WindowHelp:      v.openHelp,
+		presenter.WindowFunctions: v.openFunctions,
+		presenter.WindowMemory:    v.openMemory,
+	}
+	for _, name := range session.Windows {
+		if openWindow, ok := open[name]; ok {
+			openWindow()
+		}
+	}
+}
+
+// switchSession сохраняет текущий сеанс, закрывает его окна и открывает другой.
+func (v *View) switchSession(name string, parent fyne.Window) {
+	if err := v.presenter.SaveSession(); err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to save session: %v", err), parent)
+		return
+	}
+	for windowName, window := range v.windows {
+		v.closeAuxWindow(windowName, window)
+	}
+	session, err := v.presenter.LoadSession(name)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to open session: %v", err), parent)
+		return
+	}
+	v.applySession(session)
+}
+
+func (v *View) openSessions() {
+	sessionsWindow := fyne.CurrentApp().NewWindow("Sessions")
+	v.showSessions(sessionsWindow)
+}
+
+func (v *View) showSessions(mainWindow fyne.Window) {
+	currentLabel := widget.NewLabel("")
+	sessionSelect := widget.NewSelect(nil, nil)
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("New session name")
+
+	refresh := func() {
+		currentLabel.SetText("Current session: " + v.presenter.SessionName())
+		names, err := v.presenter.SessionNames()
+		if err != nil {
+			currentLabel.SetText(err.Error())
+		}
+		sessionSelect.Options = names
+		sessionSelect.ClearSelected()
+		sessionSelect.Refresh()
+	}
+	refresh()
+
+	openButton := widget.NewButton("Open", func() {
+		if sessionSelect.Selected == "" {
+			return
+		}
+		v.switchSession(sessionSelect.Selected, mainWindow)
+		refresh()
+	})
+	deleteButton := widget.NewButton("Delete", func() {
+		name := sessionSelect.Selected
+		if name == "" {
+			return
+		}
+		dialog.ShowConfirm("Confirm", fmt.Sprintf("Delete session %q?", name), func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			if err := v.presenter.DeleteSession(name); err != nil {
+				dialog.ShowError(err, mainWindow)
+			}
+			refresh()
+		}, mainWindow)
+	})
+	saveButton := widget.NewButton("Save As", func() {
+		if err := v.presenter.SaveSessionAs(nameEntry.Text); err != nil {
+			dialog.ShowError(err, mainWindow)
+			return
+		}
+		nameEntry.SetText("")
+		refresh()
+	})
+
+	mainWindow.SetContent(container.NewVBox(
+		currentLabel,
+		container.NewBorder(nil, nil, nil, container.NewHBox(openButton, deleteButton), sessionSelect),
+		container.NewBorder(nil, nil, nil, saveButton, nameEntry),
+	))
+	mainWindow.Resize(fyne.NewSize(360, 150))
+	mainWindow.CenterOnScreen()
+	mainWindow.Show()
+}
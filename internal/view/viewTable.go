@@ -0,0 +1,131 @@
+package view
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/presenter"
+)
+
+// openXTable показывает выбранные выражения рядом друг с другом:
+// строка таблицы — значение x, столбец — выражение.
+func (v *View) openXTable(expressions []string) {
+	tableWindow := fyne.CurrentApp().NewWindow("Compare")
+	v.showXTable(tableWindow, expressions)
+}
+
+func (v *View) showXTable(mainWindow fyne.Window, expressions []string) {
+	// table, generation и cancelEvaluation меняются из фонового вычисления,
+	// поэтому читаются и пишутся только под mu
+	var table presenter.XTable
+	var generation int
+	var mu sync.Mutex
+	var cancelEvaluation context.CancelFunc
+
+	xEntry := widget.NewEntry()
+	xEntry.SetText(v.presenter.X())
+	xEntry.SetPlaceHolder("x values: 1, 2.5, 0..10:2")
+	statusLabel := widget.NewLabel("")
+
+	cellText := func(id widget.TableCellID) string {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case id.Row == 0 && id.Col == 0:
+			return "x"
+		case id.Row == 0:
+			return getTruncatedLegendLabel(expressions[id.Col-1])
+		case id.Row > len(table.X):
+			// Таблицу заменили между запросом размера и отрисовкой ячейки
+			return ""
+		case id.Col == 0:
+			return strconv.FormatFloat(table.X[id.Row-1], 'g', -1, 64)
+		default:
+			return table.Values[id.Row-1][id.Col-1]
+		}
+	}
+
+	valuesTable := widget.NewTable(
+		func() (int, int) {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(table.X) + 1, len(expressions) + 1
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.TableCellID, obj fyne.CanvasObject) {
+			label := obj.(*widget.Label)
+			label.TextStyle = fyne.TextStyle{Bold: id.Row == 0 || id.Col == 0}
+			label.SetText(cellText(id))
+		},
+	)
+	valuesTable.SetColumnWidth(0, 80)
+	for col := 1; col <= len(expressions); col++ {
+		valuesTable.SetColumnWidth(col, 140)
+	}
+
+	// Вычисление идет в фоне; новый запуск отменяет предыдущий, а результат
+	// устаревшего запуска отбрасывается по номеру запуска
+	evaluate := func() {
+		xs, err := presenter.ParseXValues(xEntry.Text)
+		if err != nil {
+			statusLabel.SetText(err.Error())
+			return
+		}
+
+		mu.Lock()
+		if cancelEvaluation != nil {
+			cancelEvaluation()
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancelEvaluation = cancel
+		generation++
+		run := generation
+		mu.Unlock()
+
+		statusLabel.SetText("Calculating...")
+		go func() {
+			result, err := v.presenter.EvaluateTable(ctx, expressions, xs)
+			mu.Lock()
+			stale := run != generation || ctx.Err() != nil
+			if !stale && err == nil {
+				table = result
+			}
+			mu.Unlock()
+			if stale {
+				return
+			}
+			if err != nil {
+				statusLabel.SetText(err.Error())
+				return
+			}
+			statusLabel.SetText("")
+			valuesTable.Refresh()
+		}()
+	}
+	xEntry.OnSubmitted = func(string) { evaluate() }
+	mainWindow.SetOnClosed(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if cancelEvaluation != nil {
+			cancelEvaluation()
+		}
+	})
+	evaluate()
+
+	evaluateButton := widget.NewButton("Evaluate", evaluate)
+	controls := container.NewVBox(
+		container.NewBorder(nil, nil, widget.NewLabel("x:"), evaluateButton, xEntry),
+		statusLabel,
+	)
+
+	mainWindow.SetContent(container.NewBorder(controls, nil, nil, nil, valuesTable))
+	mainWindow.Resize(fyne.NewSize(float32(min(100+140*len(expressions), 800)), 420))
+	mainWindow.CenterOnScreen()
+	mainWindow.Show()
+}
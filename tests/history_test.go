@@ -0,0 +1,713 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/history"
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/presenter"
+)
+
+func TestHistoryEntries(t *testing.T) {
+	file := history.NewFile(filepath.Join(t.TempDir(), "history.jsonl"))
+
+	if entries, err := file.Entries(); err != nil || len(entries) != 0 {
+		t.Fatalf("Missing history file: %v (%v), expected empty history", entries, err)
+	}
+
+	entries := []history.Entry{
+		{Kind: history.KindExpression, Expression: "2 + 3", Result: "5", X: "1", Mode: history.Mode{Exact: true}},
+		{Kind: history.KindPlot, Expression: "sin(x)"},
+	}
+	for _, entry := range entries {
+		if err := file.Append(entry); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	got, err := file.Entries()
+	if err != nil || len(got) != 2 {
+		t.Fatalf("Entries = %+v (%v), expected 2 entries", got, err)
+	}
+	// Выражение с пробелом остается одной записью
+	if got[0].Expression != "2 + 3" || got[0].Result != "5" || got[0].X != "1" || !got[0].Mode.Exact {
+		t.Errorf("First entry = %+v", got[0])
+	}
+	if got[1].Kind != history.KindPlot || got[1].Version != history.FormatVersion || got[1].Time.IsZero() {
+		t.Errorf("Second entry = %+v", got[1])
+	}
+}
+
+func TestHistoryMigratesPlainText(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.txt")
+	if err := os.WriteFile(path, []byte("2+3\nsin(x)\n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	file := history.NewFile(path)
+	if err := file.Append(history.Entry{Kind: history.KindExpression, Expression: "7*6", Result: "42"}); err != nil {
+		t.Fatalf("Append to a plain-text file failed: %v", err)
+	}
+
+	entries, err := file.Entries()
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("Entries after migration = %+v (%v), expected 3", entries, err)
+	}
+	for i, expected := range []string{"2+3", "sin(x)", "7*6"} {
+		if entries[i].Expression != expected || entries[i].Kind != history.KindExpression {
+			t.Errorf("Entry %d = %+v, expected expression %s", i, entries[i], expected)
+		}
+	}
+
+	content, _ := os.ReadFile(path)
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		if !strings.HasPrefix(line, `{"v":1,`) {
+			t.Errorf("Line %q was not migrated to JSON", line)
+		}
+	}
+}
+
+func TestPresenterHistoryKinds(t *testing.T) {
+	config := testConfig(t)
+	p := newTestPresenter(t, config)
+
+	p.SetUseExact(true)
+	evaluate(p, "1/4+1/4")
+	p.LoadExpression("x^2")
+	p.RecordPlot()
+	if _, err := p.CalculateCredit("Annuity", 100000, 12, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := presenter.NewPresenter(nil, nil, config).HistoryEntries()
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("History = %+v (%v), expected 3 entries", entries, err)
+	}
+	if e := entries[0]; e.Kind != history.KindExpression || e.Expression != "1/4+1/4" || e.Result != "0.5" || !e.Mode.Exact {
+		t.Errorf("Expression entry = %+v", e)
+	}
+	if e := entries[1]; e.Kind != history.KindPlot || e.Expression != "x^2" {
+		t.Errorf("Plot entry = %+v", e)
+	}
+	if e := entries[2]; e.Kind != history.KindCredit || !strings.Contains(e.Expression, "Annuity") || !strings.Contains(e.Result, "MonthlyPayment=8791.59") {
+		t.Errorf("Credit entry = %+v", e)
+	}
+}
+
+func TestPresenterSkipsFailedHistory(t *testing.T) {
+	config := testConfig(t)
+	p := newTestPresenter(t, config)
+
+	evaluate(p, "2**3")
+	p.SetUseExact(true)
+	evaluate(p, "1/0")
+	evaluate(p, "1+1")
+
+	entries, err := p.HistoryEntries()
+	if err != nil || len(entries) != 1 || entries[0].Expression != "1+1" || entries[0].Result != "2" {
+		t.Errorf("History = %+v (%v), expected only 1+1 = 2", entries, err)
+	}
+}
+
+func TestHistoryFilterAndGroups(t *testing.T) {
+	day := func(d, hour int) time.Time { return time.Date(2026, 10, d, hour, 0, 0, 0, time.Local) }
+	entries := []history.Entry{
+		{Time: day(17, 9), Kind: history.KindExpression, Expression: "sin(x)+1", Result: "1"},
+		{Time: day(18, 10), Kind: history.KindPlot, Expression: "SIN(x)"},
+		{Time: day(18, 12), Kind: history.KindCredit, Expression: "Annuity: sum=1000", Result: "Overpay=10.00"},
+		{Time: day(19, 8), Kind: history.KindExpression, Expression: "2^10", Result: "1024"},
+	}
+
+	tests := []struct {
+		name     string
+		filter   history.Filter
+		expected []string
+	}{
+		{"all", history.Filter{}, []string{"sin(x)+1", "SIN(x)", "Annuity: sum=1000", "2^10"}},
+		{"substring ignores case", history.Filter{Query: "sin"}, []string{"sin(x)+1", "SIN(x)"}},
+		{"result is searched", history.Filter{Query: "1024"}, []string{"2^10"}},
+		{"regex", history.Filter{Query: `^\d+\^`, Regex: true}, []string{"2^10"}},
+		{"kind", history.Filter{Kinds: []history.Kind{history.KindPlot, history.KindCredit}}, []string{"SIN(x)", "Annuity: sum=1000"}},
+		{"date range", history.Filter{From: day(18, 0), To: day(19, 0)}, []string{"SIN(x)", "Annuity: sum=1000"}},
+	}
+	for _, tt := range tests {
+		got, err := tt.filter.Apply(entries)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var expressions []string
+		for _, entry := range got {
+			expressions = append(expressions, entry.Expression)
+		}
+		if strings.Join(expressions, "|") != strings.Join(tt.expected, "|") {
+			t.Errorf("%s: got %v, expected %v", tt.name, expressions, tt.expected)
+		}
+	}
+
+	if _, err := (history.Filter{Query: "(", Regex: true}).Apply(entries); err == nil {
+		t.Errorf("Invalid regex should be reported")
+	}
+
+	groups := history.GroupByDay(entries)
+	if len(groups) != 3 || !groups[0].Day.Equal(day(19, 0)) || len(groups[1].Entries) != 2 || groups[1].Entries[0].Expression != "SIN(x)" {
+		t.Errorf("GroupByDay = %+v, expected 3 days, newest first", groups)
+	}
+}
+
+func TestPresenterInsertFromHistory(t *testing.T) {
+	p := newTestPresenter(t, testConfig(t))
+
+	p.InsertExpression("1+2")
+	if got := p.Display(); got != "1+2" {
+		t.Errorf("Insert into empty display = %s, expected 1+2", got)
+	}
+	p.LoadExpression("10-")
+	p.InsertExpression("3")
+	if got := evaluate(p, p.Display()); got != "7" {
+		t.Errorf("10-3 = %s, expected 7", got)
+	}
+	p.LoadExpression("2*")
+	p.InsertExpression("1+2")
+	if got := evaluate(p, p.Display()); got != "6" {
+		t.Errorf("Inserted compound expression should keep precedence: 2*(1+2) = %s", got)
+	}
+}
+
+func TestHistoryRetention(t *testing.T) {
+	file := history.NewFile(filepath.Join(t.TempDir(), "history.jsonl"))
+	file.SetRetention(history.Retention{MaxEntries: 3, MaxAge: 48 * time.Hour})
+
+	now := time.Now()
+	appends := []history.Entry{
+		{Kind: history.KindExpression, Expression: "old", Time: now.Add(-72 * time.Hour)},
+		{Kind: history.KindExpression, Expression: "1+1", Result: "2"},
+		{Kind: history.KindExpression, Expression: "2+2", Result: "4"},
+		{Kind: history.KindExpression, Expression: "2+2", Result: "4"},
+		{Kind: history.KindExpression, Expression: "3+3", Result: "6"},
+		{Kind: history.KindExpression, Expression: "4+4", Result: "8"},
+	}
+	for _, entry := range appends {
+		if err := file.Append(entry); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	// Повтор 2+2 схлопнут, старая запись и лишняя запись удалены из файла
+	content, err := os.ReadFile(file.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(content), "\n"); lines != 3 {
+		t.Errorf("History file has %d lines, expected 3:\n%s", lines, content)
+	}
+	entries, err := file.Entries()
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("Entries = %+v (%v), expected 3", entries, err)
+	}
+	for i, expected := range []string{"2+2", "3+3", "4+4"} {
+		if entries[i].Expression != expected {
+			t.Errorf("Entry %d = %q, expected %q", i, entries[i].Expression, expected)
+		}
+	}
+
+	// Та же запись с другим x — отдельное вычисление
+	if err := file.Append(history.Entry{Kind: history.KindExpression, Expression: "4+4", Result: "8", X: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := file.Entries(); len(entries) != 3 || entries[2].X != "1" {
+		t.Errorf("Entries after x change = %+v", entries)
+	}
+}
+
+func TestHistoryParseAge(t *testing.T) {
+	tests := map[string]time.Duration{"30d": 30 * 24 * time.Hour, "12h": 12 * time.Hour, "0d": 0}
+	for value, expected := range tests {
+		if age, err := history.ParseAge(value); err != nil || age != expected {
+			t.Errorf("ParseAge(%q) = %v (%v), expected %v", value, age, err, expected)
+		}
+	}
+	for _, value := range []string{"", "d", "-1d", "week"} {
+		if _, err := history.ParseAge(value); err == nil {
+			t.Errorf("ParseAge(%q) succeeded, expected an error", value)
+		}
+	}
+}
+
+func TestPresenterSkipsIntermediateHistory(t *testing.T) {
+	config := testConfig(t)
+	config.HistorySkipIntermediate = true
+	p := newTestPresenter(t, config)
+
+	p.LoadExpression("2+3")
+	p.InverseSign()
+	p.LoadExpression("x^2")
+	p.RecordPlot()
+	evaluate(p, "7*6")
+
+	entries, err := p.HistoryEntries()
+	if err != nil || len(entries) != 1 || entries[0].Expression != "7*6" {
+		t.Fatalf("History = %+v (%v), expected only 7*6", entries, err)
+	}
+}
+
+func TestPresenterInverseSignHistory(t *testing.T) {
+	p := newTestPresenter(t, testConfig(t))
+
+	// Неудачное вычисление в историю не попадает
+	p.LoadExpression("sqrt(-4)")
+	p.InverseSign()
+	p.LoadExpression("2+3")
+	p.InverseSign()
+	if p.Display() != "-5" {
+		t.Fatalf("+/- of 2+3 = %s, expected -5", p.Display())
+	}
+
+	entries, err := p.HistoryEntries()
+	if err != nil || len(entries) != 1 || entries[0].Expression != "(2+3)*(-1)" || entries[0].Result != "-5" {
+		t.Fatalf("History = %+v (%v), expected only (2+3)*(-1) = -5", entries, err)
+	}
+}
+
+func TestHistoryPinnedAndNotes(t *testing.T) {
+	file := history.NewFile(filepath.Join(t.TempDir(), "history.jsonl"))
+	old := history.Entry{Kind: history.KindExpression, Expression: "220*16", Result: "3520", Time: time.Now().Add(-72 * time.Hour)}
+	if err := file.Append(old); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.SetPinned(old, true); err != nil {
+		t.Fatalf("SetPinned failed: %v", err)
+	}
+	if err := file.SetNote(old, "  Q3 cable load estimate "); err != nil {
+		t.Fatalf("SetNote failed: %v", err)
+	}
+	if err := file.SetNote(history.Entry{Expression: "missing"}, "note"); err == nil {
+		t.Error("SetNote for a missing entry succeeded, expected an error")
+	}
+
+	// Закрепленная запись переживает ограничения по сроку и числу
+	file.SetRetention(history.Retention{MaxEntries: 1, MaxAge: 24 * time.Hour})
+	for _, expression := range []string{"1+1", "2+2"} {
+		if err := file.Append(history.Entry{Kind: history.KindExpression, Expression: expression}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := file.Entries()
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Entries = %+v (%v), expected the pinned entry and 2+2", entries, err)
+	}
+	if e := entries[0]; !e.Pinned || e.Note != "Q3 cable load estimate" || entries[1].Expression != "2+2" {
+		t.Errorf("Entries = %+v", entries)
+	}
+
+	// Повтор закрепленной записи остается закрепленным
+	if err := file.SetPinned(entries[1], true); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Append(history.Entry{Kind: history.KindExpression, Expression: "2+2"}); err != nil {
+		t.Fatal(err)
+	}
+	if repeated, _ := file.Entries(); len(repeated) != 2 || !repeated[1].Pinned || repeated[1].Time.Equal(entries[1].Time) {
+		t.Errorf("Entries after a repeat = %+v", repeated)
+	}
+
+	found, err := history.Filter{Query: "cable"}.Apply(entries)
+	if err != nil || len(found) != 1 || found[0].Expression != "220*16" {
+		t.Errorf("Search by note = %+v (%v)", found, err)
+	}
+}
+
+func TestPresenterExportPinned(t *testing.T) {
+	config := testConfig(t)
+	p := newTestPresenter(t, config)
+	evaluate(p, "2+3")
+	evaluate(p, "7*6")
+
+	entries, err := p.HistoryEntries()
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("History = %+v (%v)", entries, err)
+	}
+	if err := p.PinHistoryEntry(entries[1], true); err != nil {
+		t.Fatal(err)
+	}
+	if pinned, err := p.PinnedHistory(history.Filter{}); err != nil || len(pinned) != 1 || pinned[0].Expression != "7*6" {
+		t.Errorf("PinnedHistory = %+v (%v)", pinned, err)
+	}
+
+	var exported strings.Builder
+	if err := p.ExportHistory(&exported, history.ExportJSONLines, history.Filter{Pinned: true}); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(exported.String()), "\n"); len(lines) != 1 || !strings.Contains(lines[0], `"expression":"7*6"`) || !strings.Contains(lines[0], `"pinned":true`) {
+		t.Errorf("Exported pinned history:\n%s", exported.String())
+	}
+}
+
+func TestHistoryExportFormats(t *testing.T) {
+	at := time.Date(2026, 3, 14, 15, 9, 26, 0, time.UTC)
+	entries := []history.Entry{
+		{Time: at, Kind: history.KindExpression, Expression: "2|3", Result: "3", X: "1", Pinned: true, Note: "a, \"b\""},
+		{Time: at.Add(time.Minute), Kind: history.KindPlot, Expression: "sin(x)"},
+	}
+
+	var csvOut strings.Builder
+	if err := history.Export(&csvOut, entries, history.ExportCSV); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(csvOut.String()), "\n"); len(lines) != 3 || lines[0] != "time,kind,expression,result,x,pinned,note" || lines[1] != `2026-03-14T15:09:26Z,expression,2|3,3,1,true,"a, ""b"""` {
+		t.Errorf("CSV export:\n%s", csvOut.String())
+	}
+
+	var markdown strings.Builder
+	if err := history.Export(&markdown, entries, history.ExportMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(markdown.String()), "\n"); len(lines) != 4 || !strings.Contains(lines[2], "| expression | `2\\|3` | 3 | 1 | yes |") {
+		t.Errorf("Markdown export:\n%s", markdown.String())
+	}
+
+	// Выгрузки CSV и JSON читаются обратно без потерь
+	dir := t.TempDir()
+	for _, format := range []history.ExportFormat{history.ExportCSV, history.ExportJSON, history.ExportJSONLines} {
+		path := filepath.Join(dir, "export."+string(format))
+		var content strings.Builder
+		if err := history.Export(&content, entries, format); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+		read, err := history.ReadFile(path)
+		if err != nil || len(read) != 2 {
+			t.Fatalf("ReadFile(%s) = %+v (%v)", format, read, err)
+		}
+		if e := read[0]; !e.Time.Equal(at) || e.Expression != "2|3" || e.Note != entries[0].Note || !e.Pinned || e.Version != history.FormatVersion {
+			t.Errorf("ReadFile(%s) first entry = %+v", format, e)
+		}
+	}
+	if history.FormatFromPath("history.MD") != history.ExportMarkdown || history.FormatFromPath("history.txt") != history.ExportJSONLines {
+		t.Error("FormatFromPath picked a wrong format")
+	}
+}
+
+func TestPresenterImportHistory(t *testing.T) {
+	config := testConfig(t)
+	p := newTestPresenter(t, config)
+	evaluate(p, "2+3")
+
+	// Файл другого компьютера: своя запись, общая запись и повтор
+	local, err := p.HistoryEntries()
+	if err != nil || len(local) != 1 {
+		t.Fatalf("History = %+v (%v)", local, err)
+	}
+	other := history.NewFile(filepath.Join(t.TempDir(), "other.txt"))
+	for _, entry := range []history.Entry{
+		local[0],
+		{Kind: history.KindExpression, Expression: "7*6", Result: "42", Time: local[0].Time.Add(-time.Hour)},
+	} {
+		if err := other.Append(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, expected := range []int{1, 0} {
+		added, err := p.ImportHistory(other.Path())
+		if err != nil || added != expected {
+			t.Errorf("Import %d added %d entries (%v), expected %d", i+1, added, err, expected)
+		}
+	}
+	entries, err := p.HistoryEntries()
+	if err != nil || len(entries) != 2 || entries[0].Expression != "7*6" || entries[1].Expression != "2+3" {
+		t.Errorf("History after import = %+v (%v), expected 7*6 and 2+3 by time", entries, err)
+	}
+}
+
+func TestPresenterImportLegacyHistoryTwice(t *testing.T) {
+	config := testConfig(t)
+	p := newTestPresenter(t, config)
+
+	// У старого формата нет времени: записи получают дату изменения файла
+	path := filepath.Join(t.TempDir(), "old.txt")
+	if err := os.WriteFile(path, []byte("1+2\nsin(x)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []int{2, 0} {
+		touched := time.Now().Add(time.Duration(i-2) * time.Hour)
+		if err := os.Chtimes(path, touched, touched); err != nil {
+			t.Fatal(err)
+		}
+		added, err := p.ImportHistory(path)
+		if err != nil || added != expected {
+			t.Errorf("Import %d added %d entries (%v), expected %d", i+1, added, err, expected)
+		}
+	}
+}
+
+func TestHistoryConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.txt")
+
+	// Каждый писатель — отдельный File, как у двух запущенных калькуляторов
+	const writers, perWriter = 4, 25
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			file := history.NewFile(path)
+			for i := 0; i < perWriter; i++ {
+				// Перезапись файла (закладка) чередуется с дописыванием
+				entry := history.Entry{Kind: history.KindExpression, Expression: fmt.Sprintf("%d+%d", w, i)}
+				if err := file.Append(entry); err != nil {
+					t.Errorf("Append failed: %v", err)
+					return
+				}
+				if i%2 == 0 {
+					entries, err := file.Entries()
+					if err == nil && len(entries) > 0 {
+						err = file.SetPinned(entries[len(entries)-1], true)
+					}
+					if err != nil {
+						t.Errorf("Pinning failed: %v", err)
+						return
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	entries, err := history.NewFile(path).Entries()
+	if err != nil || len(entries) != writers*perWriter {
+		t.Fatalf("History has %d entries (%v), expected %d", len(entries), err, writers*perWriter)
+	}
+}
+
+func TestHistoryWatch(t *testing.T) {
+	config := testConfig(t)
+	p := presenter.NewPresenter(nil, nil, config)
+
+	changes := make(chan struct{}, 10)
+	stop, err := p.WatchHistory(func() { changes <- struct{}{} })
+	if err != nil {
+		t.Fatalf("WatchHistory failed: %v", err)
+	}
+	defer stop()
+
+	other := history.NewFile(config.HistoryFilePath)
+	if err := other.Append(history.Entry{Kind: history.KindExpression, Expression: "2+3"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changes:
+	case <-time.After(2 * time.Second):
+		t.Fatal("No notification about an entry added by another writer")
+	}
+
+	stop()
+	if err := other.Append(history.Entry{Kind: history.KindExpression, Expression: "7*6"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changes:
+		t.Error("Notification after the watch was stopped")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	if _, err := presenter.NewPresenter(nil, nil, presenter.Config{}).WatchHistory(func() {}); err == nil {
+		t.Error("WatchHistory without a history file succeeded, expected an error")
+	}
+}
+
+func TestHistoryStores(t *testing.T) {
+	dir := t.TempDir()
+	stores := map[string]func(history.Retention) history.Store{
+		"file": func(r history.Retention) history.Store {
+			store, _ := history.OpenStore(history.BackendFile, filepath.Join(dir, "history.txt"), r)
+			return store
+		},
+		"bolt": func(r history.Retention) history.Store {
+			store, _ := history.OpenStore(history.BackendBolt, filepath.Join(dir, "history.db"), r)
+			return store
+		},
+		"memory": func(r history.Retention) history.Store {
+			store := history.NewMemory()
+			store.SetRetention(r)
+			return store
+		},
+	}
+
+	now := time.Now()
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := open(history.Retention{MaxEntries: 2})
+			changes := make(chan struct{}, 100)
+			stop, err := store.Watch(func() { changes <- struct{}{} })
+			if err != nil {
+				t.Fatalf("Watch failed: %v", err)
+			}
+			defer stop()
+
+			old := history.Entry{Kind: history.KindExpression, Expression: "1+1", Result: "2", Time: now.Add(-time.Hour)}
+			for _, entry := range []history.Entry{old, {Kind: history.KindPlot, Expression: "x^2"}, {Kind: history.KindPlot, Expression: "x^2"}} {
+				if err := store.Append(entry); err != nil {
+					t.Fatalf("Append failed: %v", err)
+				}
+			}
+			if err := store.SetPinned(old, true); err != nil {
+				t.Fatalf("SetPinned failed: %v", err)
+			}
+			if err := store.SetNote(old, " two "); err != nil {
+				t.Fatalf("SetNote failed: %v", err)
+			}
+			if err := store.Append(history.Entry{Kind: history.KindExpression, Expression: "3*3", Result: "9"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Append(history.Entry{Kind: history.KindExpression, Expression: "4*4", Result: "16"}); err != nil {
+				t.Fatal(err)
+			}
+
+			// Повтор x^2 схлопнут, закрепленная 1+1 не считается в MaxEntries
+			entries, err := store.Entries()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Expression)
+			}
+			if strings.Join(got, " ") != "1+1 3*3 4*4" || !entries[0].Pinned || entries[0].Note != "two" {
+				t.Errorf("Entries = %v (%+v)", got, entries[0])
+			}
+
+			added, err := store.Merge([]history.Entry{entries[1], {Kind: history.KindExpression, Expression: "5*5", Time: now.Add(-2 * time.Hour)}})
+			if err != nil || added != 1 {
+				t.Errorf("Merge added %d (%v), expected 1", added, err)
+			}
+			if entries, _ := store.Entries(); len(entries) != 3 || entries[0].Expression != "1+1" {
+				t.Errorf("Entries after merge = %+v", entries)
+			}
+
+			if err := store.Clear(); err != nil {
+				t.Fatal(err)
+			}
+			if entries, err := store.Entries(); err != nil || len(entries) != 0 {
+				t.Errorf("Entries after Clear = %+v (%v)", entries, err)
+			}
+			select {
+			case <-changes:
+			case <-time.After(2 * time.Second):
+				t.Error("No change notifications")
+			}
+		})
+	}
+
+	// Без ограничений повтор схлопывается по последнему ключу базы
+	unlimited, err := history.OpenStore(history.BackendBolt, filepath.Join(dir, "unlimited.db"), history.Retention{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expression := range []string{"x^2", "x^3", "x^3"} {
+		if err := unlimited.Append(history.Entry{Kind: history.KindPlot, Expression: expression}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if entries, err := unlimited.Entries(); err != nil || len(entries) != 2 || entries[1].Expression != "x^3" {
+		t.Errorf("Unlimited bolt entries = %+v (%v), expected x^2 and x^3", entries, err)
+	}
+
+	if _, err := history.OpenStore("sqlite", filepath.Join(dir, "history.sqlite"), history.Retention{}); err == nil {
+		t.Error("OpenStore with an unknown backend succeeded, expected an error")
+	}
+}
+
+func TestPresenterInjectedHistoryStore(t *testing.T) {
+	store := history.NewMemory()
+	config := testConfig(t)
+	config.HistoryStore = store
+	p := newTestPresenter(t, config)
+
+	evaluate(p, "2+3")
+	entries, err := store.Entries()
+	if err != nil || len(entries) != 1 || entries[0].Result != "5" {
+		t.Fatalf("Injected store = %+v (%v)", entries, err)
+	}
+	if _, err := os.Stat(config.HistoryFilePath); !os.IsNotExist(err) {
+		t.Errorf("History file was written although a store was injected: %v", err)
+	}
+	if err := p.ClearHistory(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := p.HistoryEntries(); len(entries) != 0 {
+		t.Errorf("History after Clear = %+v", entries)
+	}
+}
+
+func TestParseXValues(t *testing.T) {
+	tests := map[string][]float64{
+		"2":           {2},
+		"1, 2.5; -3":  {1, 2.5, -3},
+		"0..1:0.25":   {0, 0.25, 0.5, 0.75, 1},
+		"3..1 10":     {3, 2, 1, 10},
+		"0..0.3:0.1":  {0, 0.1, 0.2, 0.30000000000000004},
+		" 1e3 ,\t-0 ": {1000, 0},
+	}
+	for text, expected := range tests {
+		got, err := presenter.ParseXValues(text)
+		if err != nil || !slices.Equal(got, expected) {
+			t.Errorf("ParseXValues(%q) = %v (%v), expected %v", text, got, err, expected)
+		}
+	}
+	for _, text := range []string{"", "x", "1..", "0..1:0", "0..1:-1", "0..1000000:0.001", "0..1:NaN"} {
+		if _, err := presenter.ParseXValues(text); err == nil {
+			t.Errorf("ParseXValues(%q) succeeded, expected an error", text)
+		}
+	}
+
+	// Число точек не помещается в int: ошибка, а не пустой список
+	if values, err := presenter.ParseXValues("1..1e300:1e-300"); err == nil || !strings.Contains(err.Error(), "too many values") {
+		t.Errorf("ParseXValues(1..1e300:1e-300) = %d values (%v), expected a too many values error", len(values), err)
+	}
+}
+
+func TestPresenterEvaluateHistoryTable(t *testing.T) {
+	config := testConfig(t)
+	p := newTestPresenter(t, config)
+	p.LoadExpression("x^2")
+	p.RecordPlot()
+	evaluate(p, "2*x+1")
+
+	entries, err := p.HistoryEntries()
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("History = %+v (%v)", entries, err)
+	}
+	expressions := []string{entries[0].Expression, entries[1].Expression, "sqrt(x)"}
+	table, err := p.EvaluateTable(context.Background(), expressions, []float64{-1, 0, 3})
+	if err != nil {
+		t.Fatalf("EvaluateTable failed: %v", err)
+	}
+
+	expected := [][]string{
+		{"1", "-1", "Error"},
+		{"0", "1", "0"},
+		{"9", "7", "1.73205080756888"},
+	}
+	for i, row := range expected {
+		if !slices.Equal(table.Values[i], row) {
+			t.Errorf("Row x=%g = %v, expected %v", table.X[i], table.Values[i], row)
+		}
+	}
+
+	if table, err := p.EvaluateTable(context.Background(), []string{"1/"}, []float64{1}); err != nil || table.Values[0][0] != "Error" {
+		t.Errorf("Invalid expression cell = %v (%v), expected Error", table.Values, err)
+	}
+	if _, err := p.EvaluateTable(context.Background(), nil, []float64{1}); err == nil {
+		t.Error("EvaluateTable without expressions succeeded, expected an error")
+	}
+}
@@ -1,17 +1,27 @@
 package test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/joho/godotenv"
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/helpers"
 	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/model"
 )
 
 func TestMain(m *testing.M) {
+	if model.ServeWorkerIfRequested() {
+		os.Exit(0)
+	}
+
 	err := godotenv.Load("../.env")
 	if err != nil {
 		log.Fatalf("Error loading .env file: %v", err)
@@ -230,3 +240,427 @@ func TestCalcError(t *testing.T) {
 		})
 	}
 }
+
+func TestCalculateExact(t *testing.T) {
+	expressions := []string{
+		"1/3+1/6",
+		"0.1+0.2",
+		"2^-2",
+		"(2/3)^3",
+		"7%3",
+		"-7/2%2",
+		"x*x-1/4",
+		"3^3^2/(2^5)",
+	}
+	expectedResults := []string{
+		"1/2",
+		"3/10",
+		"1/4",
+		"8/27",
+		"1",
+		"-3/2",
+		"0",
+		"19683/32",
+	}
+
+	calc, err := model.NewModel(getModelPath())
+	if err != nil {
+		t.Fatalf("Error creating the model: %v", err)
+	}
+
+	for i, expr := range expressions {
+		t.Run(fmt.Sprintf("ExactExpr%d", i), func(t *testing.T) {
+			got, err := calc.CalculateExact(context.Background(), expr, "0.5")
+			if err != nil {
+				t.Errorf("Error calculating exact expression: %s. Error: %v", expr, err)
+			} else if got.RatString() != expectedResults[i] {
+				t.Errorf("CalcExact(%s) = %s, expected %s", expr, got.RatString(), expectedResults[i])
+			}
+		})
+	}
+}
+
+func TestCalculateExactFallback(t *testing.T) {
+	calc, err := model.NewModel(getModelPath())
+	if err != nil {
+		t.Fatalf("Error creating the model: %v", err)
+	}
+
+	for _, expr := range []string{"sqrt(4)", "pi*2", "2^0.5", "e+1"} {
+		if _, err := calc.CalculateExact(context.Background(), expr, ""); !errors.Is(err, model.ErrNotExact) {
+			t.Errorf("Expected ErrNotExact for %s, got: %v", expr, err)
+		}
+	}
+
+	// Слишком большие числа не считаются точно и не зависают
+	start := time.Now()
+	for _, expr := range []string{"((10^1000)^1000)^1000", "(10^1000)^1000", "(2^1000)^60*(2^1000)^60"} {
+		if _, err := calc.CalculateExact(context.Background(), expr, ""); !errors.Is(err, model.ErrNotExact) {
+			t.Errorf("Expected ErrNotExact for %s, got: %v", expr, err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Huge exact powers took %v", elapsed)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := calc.CalculateExact(ctx, "1/3", ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+
+	for _, expr := range []string{"1/0", "2(3)", "5c", "(1+2"} {
+		if _, err := calc.CalculateExact(context.Background(), expr, ""); err == nil || errors.Is(err, model.ErrNotExact) {
+			t.Errorf("Expected an error for %s, got: %v", expr, err)
+		}
+	}
+}
+
+func TestApproximateFraction(t *testing.T) {
+	values := []float64{0.5, 1.0 / 3.0, -0.75, 0.1 + 0.2, 22.0 / 7.0}
+	expectedResults := []string{"1/2", "1/3", "-3/4", "3/10", "22/7"}
+
+	for i, value := range values {
+		got, ok := helpers.ApproximateFraction(value, 1000000)
+		if !ok {
+			t.Errorf("ApproximateFraction(%v) failed", value)
+		} else if got.RatString() != expectedResults[i] {
+			t.Errorf("ApproximateFraction(%v) = %s, expected %s", value, got.RatString(), expectedResults[i])
+		}
+	}
+
+	if _, ok := helpers.ApproximateFraction(math.Pi, 1000); ok {
+		t.Errorf("ApproximateFraction(pi) should not find a close fraction with small denominators")
+	}
+}
+
+func TestTranslatePercent(t *testing.T) {
+	expressions := []string{
+		"200+10%",
+		"50*10%",
+		"-(2+3)mod2",
+		"2^-1+5%",
+		"sqrt(16)*50%",
+		"2*3+1",
+	}
+	expectedResults := []string{
+		"200+200*(10/100)",
+		"50*(10/100)",
+		"-(2+3)%2",
+		"2^(-1)+2^(-1)*(5/100)",
+		"sqrt(16)*(50/100)",
+		"2*3+1",
+	}
+
+	for i, expr := range expressions {
+		got, err := model.TranslatePercent(expr, model.PercentCalculator)
+		if err != nil {
+			t.Errorf("TranslatePercent(%s) failed: %v", expr, err)
+		} else if got != expectedResults[i] {
+			t.Errorf("TranslatePercent(%s) = %s, expected %s", expr, got, expectedResults[i])
+		}
+	}
+
+	if got, err := model.TranslatePercent("7%3", model.PercentModulo); err != nil || got != "7%3" {
+		t.Errorf("TranslatePercent(7%%3, modulo) = %s, %v", got, err)
+	}
+	if _, err := model.TranslatePercent("7%3", model.PercentCalculator); err == nil {
+		t.Errorf("7%%3 should be rejected when %% is a percent operator")
+	}
+}
+
+func TestFormatLaTeX(t *testing.T) {
+	tests := map[string]string{
+		"1/2+sqrt(x)":   `\frac{1}{2} + \sqrt{x}`,
+		"2*pi*(1+x)":    `2 \cdot \pi \cdot \left(1 + x\right)`,
+		"(1+2)^3":       `{\left(1 + 2\right)}^{3}`,
+		"sin(x)-(2-x)":  `\sin\left(x\right) - \left(2 - x\right)`,
+		"7mod3+asin(1)": `7 \bmod 3 + \arcsin\left(1\right)`,
+		"200+10%":       `200 + 10\%`,
+		"-1/2":          `\frac{-1}{2}`,
+		"-(1/2)":        `-\frac{1}{2}`,
+		"1.5e+10":       `1.5 \cdot 10^{10}`,
+		"dbsum(90,x)":   `\operatorname{dbsum}\left(90, x\right)`,
+	}
+	for expr, expected := range tests {
+		got, err := model.FormatLaTeX(expr, model.PercentCalculator)
+		if err != nil {
+			t.Errorf("FormatLaTeX(%s) failed: %v", expr, err)
+		} else if got != expected {
+			t.Errorf("FormatLaTeX(%s) = %s, expected %s", expr, got, expected)
+		}
+	}
+
+	if _, err := model.FormatLaTeX("1+", model.PercentCalculator); err == nil {
+		t.Errorf("FormatLaTeX should reject an incomplete expression")
+	}
+}
+
+func TestIsolatedModelSurvivesEngineCrash(t *testing.T) {
+	calc, err := model.NewIsolatedModel(getModelPath())
+	if err != nil {
+		t.Fatalf("Error creating the isolated model: %v", err)
+	}
+	defer calc.Close()
+
+	for _, expr := range []string{"c(+)", "t(+)"} {
+		crashing := expr
+		if _, err := calc.Calculate(&crashing, "0"); !errors.Is(err, model.ErrEngineCrashed) {
+			t.Errorf("Expected ErrEngineCrashed for %s, got: %v", expr, err)
+		}
+
+		valid := "2+3*(4-1)^2/3"
+		got, err := calc.Calculate(&valid, "")
+		if err != nil || got != 11 {
+			t.Errorf("Calculation after the crash = %v, %v; expected 11", got, err)
+		}
+	}
+
+	monthly, _, _, err := calc.CreditAnnuity(100000, 12, 10)
+	if err != nil || math.Abs(monthly-8791.59) > 0.01 {
+		t.Errorf("CreditAnnuity through the worker = %.2f, %v", monthly, err)
+	}
+}
+
+func TestCalculateContextLimits(t *testing.T) {
+	calc, err := model.NewModel(getModelPath())
+	if err != nil {
+		t.Fatalf("Error creating the model: %v", err)
+	}
+	calc.SetLimits(model.Limits{MaxDepth: 3, MaxTokens: 20, MaxIterations: 10})
+
+	got, err := calc.CalculateContext(context.Background(), "x^2+y", map[string]float64{"x": -3, "y": 1})
+	if err != nil || got != 10 {
+		t.Errorf("CalculateContext(x^2+y) = %v, %v; expected 10", got, err)
+	}
+
+	for _, expr := range []string{"((((1))))", "1+1+1+1+1+1+1+1+1+1+1"} {
+		if _, err := calc.CalculateContext(context.Background(), expr, nil); !errors.Is(err, model.ErrLimitExceeded) {
+			t.Errorf("Expected ErrLimitExceeded for %s, got: %v", expr, err)
+		}
+	}
+	for _, expr := range []string{"((((1))))", "1+1+1+1+1+1+1+1+1+1+1"} {
+		plain := expr
+		if _, err := calc.Calculate(&plain, ""); !errors.Is(err, model.ErrLimitExceeded) {
+			t.Errorf("Calculate: expected ErrLimitExceeded for %s, got: %v", expr, err)
+		}
+		if _, err := calc.CalculateExact(context.Background(), expr, ""); !errors.Is(err, model.ErrLimitExceeded) {
+			t.Errorf("CalculateExact: expected ErrLimitExceeded for %s, got: %v", expr, err)
+		}
+	}
+	if err := calc.Limits().CheckIterations(11); !errors.Is(err, model.ErrLimitExceeded) {
+		t.Errorf("Expected ErrLimitExceeded for 11 iterations, got: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := calc.CalculateContext(ctx, "1+1", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+}
+
+func TestEvaluateBatch(t *testing.T) {
+	inProcess, err := model.NewModel(getModelPath())
+	if err != nil {
+		t.Fatalf("Error creating the model: %v", err)
+	}
+	isolated, err := model.NewIsolatedModel(getModelPath())
+	if err != nil {
+		t.Fatalf("Error creating the isolated model: %v", err)
+	}
+	defer isolated.Close()
+
+	xs := make([]float64, 1000)
+	for i := range xs {
+		xs[i] = float64(i-500) / 10
+	}
+
+	inProcess.SetBatchWorkers(4)
+	for name, calc := range map[string]*model.Model{"in-process": inProcess, "isolated": isolated} {
+		values, errs := calc.EvaluateBatch("x^2-2*x+sin(x)", xs)
+		for i, x := range xs {
+			expected := x*x - 2*x + math.Sin(x)
+			if errs[i] != nil || math.Abs(values[i]-expected) > 1e-9 {
+				t.Errorf("%s: EvaluateBatch at x=%v = %v, %v; expected %v", name, x, values[i], errs[i], expected)
+				break
+			}
+		}
+
+		values, errs = calc.EvaluateBatch("sqrt(x)", []float64{4, -1})
+		if errs[0] != nil || values[0] != 2 || (errs[1] == nil && !math.IsNaN(values[1])) {
+			t.Errorf("%s: EvaluateBatch(sqrt(x)) = %v, %v", name, values, errs)
+		}
+
+		if _, errs := calc.EvaluateBatch("2**x", xs[:2]); errs[0] == nil || errs[1] == nil {
+			t.Errorf("%s: expected errors for an invalid expression, got: %v", name, errs)
+		}
+
+		// Пакет и одиночное вычисление подставляют отрицательный x одинаково
+		negative := []float64{-3, -0.5, -2.0658266191369858e+09}
+		for _, expression := range []string{"-x^2", "x^2", "2-x"} {
+			values, errs := calc.EvaluateBatch(expression, negative)
+			for i, x := range negative {
+				single, err := calc.CalculateContext(context.Background(), expression, map[string]float64{"x": x})
+				if errs[i] != nil || err != nil || values[i] != single {
+					t.Errorf("%s: %s at x=%v: batch %v (%v), single %v (%v)", name, expression, x, values[i], errs[i], single, err)
+				}
+			}
+		}
+	}
+
+	inProcess.SetLimits(model.Limits{MaxDepth: 32, MaxTokens: 256, MaxIterations: 10})
+	if _, errs := inProcess.EvaluateBatch("x", xs); !errors.Is(errs[0], model.ErrLimitExceeded) {
+		t.Errorf("Expected ErrLimitExceeded for %d points, got: %v", len(xs), errs[0])
+	}
+}
+
+func TestModelHotReload(t *testing.T) {
+	library, err := os.ReadFile(getModelPath())
+	if err != nil {
+		t.Fatalf("Error reading the model library: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "model.so")
+	if err := os.WriteFile(path, library, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	calc, err := model.NewIsolatedModel(path)
+	if err != nil {
+		t.Fatalf("Error creating the isolated model: %v", err)
+	}
+	defer calc.Close()
+
+	reloads := make(chan error, 4)
+	if err := calc.Watch(func(err error) { reloads <- err }); err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	waitReload := func() error {
+		select {
+		case err := <-reloads:
+			return err
+		case <-time.After(10 * time.Second):
+			t.Fatal("Timed out waiting for the engine reload")
+			return nil
+		}
+	}
+
+	if err := os.WriteFile(path, library, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitReload(); err != nil {
+		t.Errorf("Reload of a valid library failed: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("not a plugin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitReload(); err == nil {
+		t.Error("Expected the reload of a broken library to fail")
+	}
+
+	expr := "2+3*(4-1)^2/3"
+	if got, err := calc.Calculate(&expr, ""); err != nil || got != 11 {
+		t.Errorf("Calculation after the failed reload = %v, %v; expected 11", got, err)
+	}
+
+	inProcess, err := model.NewModel(getModelPath())
+	if err != nil {
+		t.Fatalf("Error creating the model: %v", err)
+	}
+	if err := inProcess.Watch(nil); !errors.Is(err, model.ErrReloadUnsupported) {
+		t.Errorf("Expected ErrReloadUnsupported for the in-process model, got: %v", err)
+	}
+}
+
+func TestEngineCapabilities(t *testing.T) {
+	inProcess, err := model.NewModel(getModelPath())
+	if err != nil {
+		t.Fatalf("Error creating the model: %v", err)
+	}
+	isolated, err := model.NewIsolatedModel(getModelPath())
+	if err != nil {
+		t.Fatalf("Error creating the isolated model: %v", err)
+	}
+	defer isolated.Close()
+
+	for name, calc := range map[string]*model.Model{"in-process": inProcess, "isolated": isolated} {
+		if version := calc.Version(); version != "3.0.0" {
+			t.Errorf("%s: Version() = %q, expected 3.0.0", name, version)
+		}
+		capabilities := calc.Capabilities()
+		if !capabilities.HasFunction("atan") || !capabilities.HasOperator("^") ||
+			!capabilities.HasMode(model.ModeCredit) || !capabilities.HasMode(model.ModeBatch) {
+			t.Errorf("%s: unexpected capabilities %+v", name, capabilities)
+		}
+		if capabilities.HasFunction("exp") {
+			t.Errorf("%s: exp must not be reported as supported", name)
+		}
+	}
+}
+
+func TestExtensionFunctions(t *testing.T) {
+	calc, err := model.NewModel(getModelPath())
+	if err != nil {
+		t.Fatalf("Error creating the model: %v", err)
+	}
+
+	hyp := func(args ...float64) (float64, error) { return math.Hypot(args[0], args[1]), nil }
+	if err := calc.RegisterFunction("hyp", 2, hyp, "hypotenuse"); err != nil {
+		t.Fatalf("RegisterFunction failed: %v", err)
+	}
+	for _, name := range []string{"hyp", "sin", "mod"} {
+		if err := calc.RegisterFunction(name, 1, hyp, ""); !errors.Is(err, model.ErrFunctionCollision) {
+			t.Errorf("Expected ErrFunctionCollision for %s, got: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		expr     string
+		x        float64
+		expected float64
+	}{
+		{"hyp(3,4)*2", 0, 10},
+		{"hyp(x,4)", -3, 5},
+		{"1+hyp(hyp(3,4),12)", 0, 14},
+		{"hyp(2^2-1,sqrt(16))", 0, 5},
+	}
+	for _, tt := range tests {
+		got, err := calc.CalculateContext(context.Background(), tt.expr, map[string]float64{"x": tt.x})
+		if err != nil || math.Abs(got-tt.expected) > 1e-9 {
+			t.Errorf("%s with x=%v = %v, %v; expected %v", tt.expr, tt.x, got, err, tt.expected)
+		}
+	}
+	if _, err := calc.CalculateContext(context.Background(), "hyp(3)", nil); err == nil {
+		t.Error("Expected an arity error for hyp(3)")
+	}
+
+	values, errs := calc.EvaluateBatch("hyp(x,4)", []float64{3, 0})
+	if errs[0] != nil || errs[1] != nil || values[0] != 5 || values[1] != 4 {
+		t.Errorf("EvaluateBatch(hyp(x,4)) = %v, %v", values, errs)
+	}
+
+	if errs := calc.LoadExtensions("../extensions"); len(errs) != 0 {
+		t.Fatalf("LoadExtensions failed: %v", errs)
+	}
+	if got, err := calc.CalculateContext(context.Background(), "db(100)+undb(10)", nil); err != nil || math.Abs(got-30) > 1e-9 {
+		t.Errorf("db(100)+undb(10) = %v, %v; expected 30", got, err)
+	}
+	errs = calc.LoadExtensions("../extensions")
+	if len(errs) != 1 || !errors.Is(errs[0], model.ErrFunctionCollision) || !strings.Contains(errs[0].Error(), "decibel.so") {
+		t.Errorf("Expected a collision naming decibel.so, got: %v", errs)
+	}
+}
+
+func TestCalculateContextPassesXAsNumber(t *testing.T) {
+	calc, err := model.NewModel(getModelPath())
+	if err != nil {
+		t.Fatalf("Error creating the model: %v", err)
+	}
+
+	for _, x := range []float64{2.0658266191369858e+09, -4.096182778849927e+10, 6.280981712183634e-18, 0.1} {
+		got, err := calc.CalculateContext(context.Background(), "x", map[string]float64{"x": x})
+		if err != nil || got != x {
+			t.Errorf("x = %v: got %v (%v)", x, got, err)
+		}
+	}
+}
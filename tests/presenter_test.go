@@ -0,0 +1,569 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/model"
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/presenter"
+)
+
+type fakeView struct {
+	renders int
+	last    presenter.State
+}
+
+func (v *fakeView) Render(state presenter.State) {
+	v.renders++
+	v.last = state
+}
+func (v *fakeView) RenderPreview(string)   {}
+func (v *fakeView) RefreshEngineFeatures() {}
+
+func testConfig(t *testing.T) presenter.Config {
+	dir := t.TempDir()
+	return presenter.Config{
+		HistoryFilePath: filepath.Join(dir, "history.txt"),
+		MemoryFilePath:  filepath.Join(dir, "memory.json"),
+	}
+}
+
+func newTestPresenter(t *testing.T, config presenter.Config) *presenter.Presenter {
+	calc, err := model.NewModel(getModelPath())
+	if err != nil {
+		t.Fatalf("Error creating the model: %v", err)
+	}
+	return presenter.NewPresenter(nil, calc, config)
+}
+
+func evaluate(p *presenter.Presenter, expression string) string {
+	p.LoadExpression(expression)
+	p.EvaluateAndProcessExpression()
+	return p.Display()
+}
+
+func TestPresenterExactMode(t *testing.T) {
+	config := testConfig(t)
+	p := newTestPresenter(t, config)
+
+	p.SetUseExact(true)
+	if got := evaluate(p, "1/3+1/6"); got != "0.5" || p.Status() != "" {
+		t.Errorf("Exact 1/3+1/6 = %s (status %q), expected 0.5", got, p.Status())
+	}
+
+	p.SetShowFraction(true)
+	if got := evaluate(p, "1/3+1/6"); got != "1/2" {
+		t.Errorf("Exact fraction 1/3+1/6 = %s, expected 1/2", got)
+	}
+
+	if evaluate(p, "sqrt(16)"); p.Status() == "" {
+		t.Errorf("Float fallback for sqrt(16) should be reported in the status label")
+	}
+}
+
+func TestPresenterAnswersAndMemory(t *testing.T) {
+	config := testConfig(t)
+	p := newTestPresenter(t, config)
+
+	if got := evaluate(p, "2+3"); got != "5" {
+		t.Fatalf("2+3 = %s, expected 5", got)
+	}
+	if got := evaluate(p, "ans*2"); got != "10" {
+		t.Errorf("ans*2 = %s, expected 10", got)
+	}
+	if got := evaluate(p, "ans1+1"); got != "6" {
+		t.Errorf("ans1+1 = %s, expected 6", got)
+	}
+
+	p.LoadExpression("4")
+	p.MemoryAdd()
+	p.MemoryAdd()
+	p.LoadExpression("1")
+	p.MemorySubtract()
+	if err := p.MemoryStore("rate"); err != nil {
+		t.Fatalf("MemoryStore failed: %v", err)
+	}
+
+	restored := newTestPresenter(t, config)
+	if value, ok := restored.MemoryValue("M"); !ok || value != 7 {
+		t.Errorf("Memory M after restart = %v, expected 7", value)
+	}
+	if value, ok := restored.MemoryValue("rate"); !ok || value != 1 {
+		t.Errorf("Memory rate after restart = %v, expected 1", value)
+	}
+
+	restored.MemoryRecall()
+	if restored.Display() != "7" {
+		t.Errorf("MR inserted %s, expected 7", restored.Display())
+	}
+	if got := evaluate(restored, "ans+0"); got != "6" {
+		t.Errorf("ans after restart = %s, expected 6", got)
+	}
+}
+
+func TestPresenterMemoryWrittenOnChange(t *testing.T) {
+	config := testConfig(t)
+	p := newTestPresenter(t, config)
+
+	// "=" меняет только ans: файл пишется при выходе, а не на каждое вычисление
+	evaluate(p, "2+3")
+	if _, err := os.Stat(config.MemoryFilePath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Memory file after = : %v, expected it not to exist", err)
+	}
+
+	p.SaveMemory()
+	info, err := os.Stat(config.MemoryFilePath)
+	if err != nil {
+		t.Fatalf("Memory file after SaveMemory: %v", err)
+	}
+	if got := evaluate(newTestPresenter(t, config), "ans+0"); got != "5" {
+		t.Errorf("ans after restart = %s, expected 5", got)
+	}
+
+	// Без изменений файл не переписывается
+	if err := os.Chtimes(config.MemoryFilePath, time.Time{}, info.ModTime().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	p.MemoryClear()
+	p.SaveMemory()
+	if after, err := os.Stat(config.MemoryFilePath); err != nil || !after.ModTime().Equal(info.ModTime().Add(-time.Hour)) {
+		t.Errorf("Unchanged memory was rewritten (%v)", err)
+	}
+}
+
+func TestPresenterPercent(t *testing.T) {
+	config := testConfig(t)
+	p := newTestPresenter(t, config)
+
+	cases := map[string]string{
+		"200+10%":      "220",
+		"200-10%":      "180",
+		"50*10%":       "5",
+		"10%":          "0.1",
+		"7mod3":        "1",
+		"(1+2)mod2":    "1",
+		"(100+50%)*2%": "3",
+		"(1+2)modx":    "error",
+		"(1+2)sin(1)":  "error",
+		"(2)x":         "error",
+	}
+	for expr, expected := range cases {
+		if got := evaluate(p, expr); got != expected {
+			t.Errorf("%s = %s, expected %s", expr, got, expected)
+		}
+	}
+
+	config.PercentMode = "modulo"
+	p = newTestPresenter(t, config)
+	if got := evaluate(p, "7%3"); got != "1" {
+		t.Errorf("Legacy 7%%3 = %s, expected 1", got)
+	}
+	if got := evaluate(p, "7mod3"); got != "1" {
+		t.Errorf("Legacy 7mod3 = %s, expected 1", got)
+	}
+}
+
+func TestPresenterDisplayPrecision(t *testing.T) {
+	config := testConfig(t)
+	p := newTestPresenter(t, config)
+
+	tests := map[string]string{
+		"0.1+0.2":     "0.3",
+		"1/3":         "0.333333333333333",
+		"2^0.5*2^0.5": "2",
+		"10^14":       "100000000000000",
+		"10^20":       "1e+20",
+		"10^300":      "1e+300",
+		"2/10^6":      "0.000002",
+		"1/10^30":     "1e-30",
+		"-1/3":        "-0.333333333333333",
+		"10^15":       "1e+15",
+		"-3/10^7":     "-3e-07",
+		"1234.5":      "1234.5",
+	}
+	for expr, expected := range tests {
+		if got := evaluate(p, expr); got != expected {
+			t.Errorf("%s = %s, expected %s", expr, got, expected)
+		}
+	}
+}
+
+func TestPresenterExtensionFunctions(t *testing.T) {
+	calc, err := model.NewModel(getModelPath())
+	if err != nil {
+		t.Fatalf("Error creating the model: %v", err)
+	}
+	double := func(args ...float64) (float64, error) { return 2 * args[0], nil }
+	if err := calc.RegisterFunction("pipe", 1, double, "doubles the argument"); err != nil {
+		t.Fatal(err)
+	}
+	p := presenter.NewPresenter(nil, calc, testConfig(t))
+
+	// Константы pi и e не подменяются внутри имени функции
+	if got := evaluate(p, "pipe(pi)+pipe(e)"); got != "11.7197489641" {
+		t.Errorf("pipe(pi)+pipe(e) = %s, expected 11.7197489641", got)
+	}
+
+	catalog := p.FunctionCatalog("pi")
+	if len(catalog) != 1 || catalog[0].Signature != "pipe(a)" {
+		t.Errorf("FunctionCatalog(pi) = %+v", catalog)
+	}
+	if help := p.ExtensionsHelp(); !strings.Contains(help, "pipe(a)") {
+		t.Errorf("ExtensionsHelp does not mention pipe: %q", help)
+	}
+}
+
+func TestPresenterHeadlessButtons(t *testing.T) {
+	config := testConfig(t)
+	p := newTestPresenter(t, config)
+
+	// Те же нажатия, что и в окне калькулятора, но без Fyne
+	for _, digit := range []string{"1", "2"} {
+		p.AppendButtonText(digit)
+	}
+	p.AddDecimalPoint()
+	p.AppendButtonText("5")
+	p.InitializeXButton()
+	if state := p.State(); state.X != "12.5" || state.Display != "0" {
+		t.Fatalf("After x<- state = %+v, expected x 12.5 and display 0", state)
+	}
+
+	p.AppendX()
+	p.AppendOperator("*")
+	p.AppendButtonText("2")
+	p.DeleteButton()
+	p.AppendButtonText("4")
+	p.EvaluateAndProcessExpression()
+	if got := p.Display(); got != "50" {
+		t.Errorf("x*4 with x=12.5 = %s, expected 50", got)
+	}
+
+	history, err := p.HistoryEntries()
+	if err != nil || len(history) != 1 || history[0].Expression != "x*4" || history[0].Result != "50" || history[0].X != "12.5" {
+		t.Errorf("History = %+v (%v), expected x*4 = 50 with x 12.5", history, err)
+	}
+	if err := p.ClearHistory(); err != nil {
+		t.Fatal(err)
+	}
+	if history, _ := p.HistoryEntries(); len(history) != 0 {
+		t.Errorf("History after clear = %v", history)
+	}
+}
+
+func TestPresenterRendersState(t *testing.T) {
+	calc, err := model.NewModel(getModelPath())
+	if err != nil {
+		t.Fatalf("Error creating the model: %v", err)
+	}
+	v := &fakeView{}
+	p := presenter.NewPresenter(v, calc, testConfig(t))
+
+	p.AppendButtonText("7")
+	p.AppendOperator("+")
+	p.AppendButtonText("1")
+	// Первая правка отрисовывается еще раз, когда становится доступна кнопка Undo
+	if v.last.Display != "7+1" || v.renders != 4 {
+		t.Errorf("View rendered %+v after %d renders, expected 7+1 after 4", v.last, v.renders)
+	}
+	p.EvaluateAndProcessExpression()
+	if v.last != p.State() || v.last.Display != "8" {
+		t.Errorf("View state %+v differs from presenter state %+v", v.last, p.State())
+	}
+}
+
+func TestPresenterCursorEditing(t *testing.T) {
+	p := newTestPresenter(t, testConfig(t))
+
+	p.LoadExpression("12+3")
+	p.MoveCursorLeft()
+	p.MoveCursorLeft()
+	p.AppendButtonText("5")
+	if state := p.State(); state.Display != "125+3" || state.Cursor != 3 {
+		t.Errorf("Insert at cursor: %+v, expected 125+3 with cursor 3", state)
+	}
+
+	p.MoveCursorHome()
+	p.AppendButtonText("sqrt(")
+	p.MoveCursorEnd()
+	p.AppendButtonText(")")
+	if got := p.Display(); got != "sqrt(125+3)" {
+		t.Errorf("Home/End editing: %s, expected sqrt(125+3)", got)
+	}
+
+	// sqrt( и pi стираются одним нажатием
+	p.SetCursor(3)
+	if state := p.State(); state.Cursor != 5 {
+		t.Errorf("SetCursor inside sqrt( = %d, expected token boundary 5", state.Cursor)
+	}
+	p.DeleteButton()
+	if state := p.State(); state.Display != "125+3)" || state.Cursor != 0 {
+		t.Errorf("Delete sqrt(: %+v, expected 125+3) with cursor 0", state)
+	}
+	p.LoadExpression("2*pi+1")
+	p.MoveCursorLeft()
+	p.MoveCursorLeft()
+	p.DeleteButton()
+	if got := p.Display(); got != "2*+1" {
+		t.Errorf("Delete pi: %s, expected 2*+1", got)
+	}
+
+	// Проверки соседних символов тоже идут от курсора
+	p.LoadExpression("2+x")
+	p.MoveCursorLeft()
+	p.AppendButtonText("3")
+	p.AppendX()
+	if got := p.Display(); got != "2+x" {
+		t.Errorf("Digit or x next to x was accepted: %s", got)
+	}
+	p.MoveCursorHome()
+	p.AddDecimalPoint()
+	p.MoveCursorRight()
+	p.AddDecimalPoint()
+	if got := p.Display(); got != ".2+x" {
+		t.Errorf("Second decimal point in one number: %s, expected .2+x", got)
+	}
+}
+
+func TestPresenterUndoRedo(t *testing.T) {
+	p := newTestPresenter(t, testConfig(t))
+
+	p.AppendButtonText("2")
+	p.AppendOperator("+")
+	p.AppendButtonText("3")
+	p.EvaluateAndProcessExpression()
+	if got := p.Display(); got != "5" {
+		t.Fatalf("2+3 = %s, expected 5", got)
+	}
+
+	// Undo после = возвращает выражение, замененное результатом
+	p.Undo()
+	if state := p.State(); state.Display != "2+3" || !state.CanRedo {
+		t.Errorf("Undo after = gave %+v, expected 2+3 with redo available", state)
+	}
+	p.Redo()
+	if got := p.Display(); got != "5" {
+		t.Errorf("Redo gave %s, expected 5", got)
+	}
+
+	p.InverseSign()
+	p.ResetButton()
+	p.Undo()
+	p.Undo()
+	if got := p.Display(); got != "5" {
+		t.Errorf("Undo of AC and +/- gave %s, expected 5", got)
+	}
+
+	// Новая правка очищает Redo; перемещение курсора правкой не считается
+	p.MoveCursorHome()
+	p.AppendButtonText("1")
+	if p.CanRedo() {
+		t.Errorf("Redo should be cleared by a new edit")
+	}
+	p.Undo()
+	if state := p.State(); state.Display != "5" || state.Cursor != 0 {
+		t.Errorf("Undo of insertion gave %+v, expected 5 with cursor 0", state)
+	}
+
+	p.MemoryAdd()
+	p.MemoryRecall()
+	p.Undo()
+	if got := p.Display(); got != "5" {
+		t.Errorf("MR should be undone in one step, got %s", got)
+	}
+
+	for p.CanUndo() {
+		p.Undo()
+	}
+	if got := p.Display(); got != "0" {
+		t.Errorf("Undo to the beginning gave %s, expected 0", got)
+	}
+}
+
+func waitPreview(p *presenter.Presenter, expected string) string {
+	deadline := time.Now().Add(2 * time.Second)
+	for p.Preview() != expected && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return p.Preview()
+}
+
+func TestPresenterLivePreview(t *testing.T) {
+	config := testConfig(t)
+	p := newTestPresenter(t, config)
+
+	p.AppendButtonText("2")
+	p.AppendOperator("*")
+	p.AppendButtonText("3")
+	if got := waitPreview(p, "6"); got != "6" {
+		t.Fatalf("Preview of 2*3 = %q, expected 6", got)
+	}
+
+	// Незаконченное выражение не сбрасывает последнее значение
+	p.AppendOperator("+")
+	time.Sleep(500 * time.Millisecond)
+	if got := p.Preview(); got != "6" {
+		t.Errorf("Preview of incomplete 2*3+ = %q, expected 6 to stay", got)
+	}
+	p.AppendButtonText("1")
+	if got := waitPreview(p, "7"); got != "7" {
+		t.Errorf("Preview of 2*3+1 = %q, expected 7", got)
+	}
+
+	// Предпросмотр не пишет историю и не меняет ans
+	if history, _ := p.HistoryEntries(); len(history) != 0 {
+		t.Errorf("Preview must not write history: %+v", history)
+	}
+	if got := evaluate(p, "ans1+0"); got == "7" {
+		t.Errorf("Preview must not be stored as an answer")
+	}
+
+	p.ResetButton()
+	if got := waitPreview(p, ""); got != "" {
+		t.Errorf("Preview after AC = %q, expected empty", got)
+	}
+}
+
+func TestPresenterPreviewKeepsRunningEvaluation(t *testing.T) {
+	calc, err := model.NewModel(getModelPath())
+	if err != nil {
+		t.Fatalf("Error creating the model: %v", err)
+	}
+	var calls atomic.Int32
+	release := make(chan struct{})
+	slow := func(args ...float64) (float64, error) {
+		calls.Add(1)
+		<-release
+		return args[0], nil
+	}
+	if err := calc.RegisterFunction("slow", 1, slow, "waits for the test"); err != nil {
+		t.Fatal(err)
+	}
+	p := presenter.NewPresenter(nil, calc, testConfig(t))
+	waitCalls := func(n int32) {
+		deadline := time.Now().Add(2 * time.Second)
+		for calls.Load() < n && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// Ввод во время медленного предпросмотра не отменяет начатое вычисление:
+	// оба предпросмотра доходят до второго вызова slow
+	p.LoadExpression("slow(1)+slow(2)")
+	waitCalls(1)
+	p.AppendOperator("*")
+	p.AppendButtonText("3")
+	close(release)
+	waitCalls(4)
+	if got := calls.Load(); got != 4 {
+		t.Errorf("Previews called slow %d times, expected 4: the running preview was cancelled", got)
+	}
+
+	p.EvaluateAndProcessExpression()
+	if got := p.Display(); got != "7" {
+		t.Errorf("slow(1)+slow(2)*3 after typing during a preview = %q, expected 7", got)
+	}
+	// Результаты устаревших предпросмотров отброшены: у числа предпросмотра нет
+	time.Sleep(500 * time.Millisecond)
+	if got := p.Preview(); got != "" {
+		t.Errorf("Stale preview %q was kept", got)
+	}
+}
+
+func TestKeyBindings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, []byte(`{"r": "sqrt", "q": "", "Ctrl+P": "History"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	bindings, err := presenter.LoadKeyBindings(path)
+	if err != nil {
+		t.Fatalf("LoadKeyBindings failed: %v", err)
+	}
+
+	expected := map[string]string{"r": "sqrt", "Ctrl+P": "History", "Return": "=", "Escape": "AC", "7": "7", "s": "sin"}
+	for key, action := range expected {
+		if bindings[key] != action {
+			t.Errorf("Binding %s = %q, expected %q", key, bindings[key], action)
+		}
+	}
+	if _, ok := bindings["q"]; ok {
+		t.Errorf("Empty action should remove the q binding")
+	}
+
+	if _, err := presenter.LoadKeyBindings(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("Missing key bindings file should be reported")
+	}
+
+	p := newTestPresenter(t, testConfig(t))
+	p.LoadExpression("12")
+	if !p.RunEditorAction(presenter.ActionCursorHome) || p.State().Cursor != 0 {
+		t.Errorf("Home action did not move the cursor: %+v", p.State())
+	}
+	if p.RunEditorAction("sqrt") {
+		t.Errorf("sqrt is a button action, not an editor action")
+	}
+}
+
+func TestPresenterClipboard(t *testing.T) {
+	p := newTestPresenter(t, testConfig(t))
+
+	tests := map[string]string{
+		"2 × 3 − 1":  "2*3-1",
+		"10 ÷ 4":     "10/4",
+		"√16 + √(x)": "sqrt(16)+sqrt(x)",
+		"3,5 * 2":    "3.5*2",
+		"2π":         "2pi",
+		" 1 000,25 ": "1000.25",
+		"sqrt(2,25)": "sqrt(2.25)",
+		"1,2345":     "1.2345",
+		"0,125":      "0.125",
+		"-0,125*2":   "-0.125*2",
+	}
+	for input, expected := range tests {
+		if got, err := p.SanitizeInput(input); err != nil || got != expected {
+			t.Errorf("SanitizeInput(%q) = %q (%v), expected %q", input, got, err, expected)
+		}
+	}
+	for _, input := range []string{"", "2 & 3", "ж+1", "rm -rf /;", "6:2", "1,000", "1,234,567", "2*1,500"} {
+		if got, err := p.SanitizeInput(input); err == nil {
+			t.Errorf("SanitizeInput(%q) = %q, expected an error", input, got)
+		}
+	}
+
+	if err := p.Paste("2 × 3"); err != nil || p.Display() != "2*3" {
+		t.Fatalf("Paste into empty display: %q (%v)", p.Display(), err)
+	}
+	p.MoveCursorHome()
+	if err := p.Paste("1 + "); err != nil || p.Display() != "1+2*3" {
+		t.Errorf("Paste at cursor: %q (%v), expected 1+2*3", p.Display(), err)
+	}
+	if err := p.Paste("$"); err == nil || p.Display() != "1+2*3" {
+		t.Errorf("Invalid paste changed the display to %q", p.Display())
+	}
+	p.Undo()
+	if got := p.Display(); got != "2*3" {
+		t.Errorf("Undo of paste gave %q, expected 2*3", got)
+	}
+
+	p.LoadExpression("1/3+1/6")
+	if got, _ := p.CopyExpression(false); got != "1/3+1/6" {
+		t.Errorf("CopyExpression = %q", got)
+	}
+	if got, _ := p.CopyExpression(true); got != `\frac{1}{3} + \frac{1}{6}` {
+		t.Errorf("CopyExpression LaTeX = %q", got)
+	}
+	p.SetShowFraction(true)
+	if got, _ := p.CopyResult(false); got != "1/2" {
+		t.Errorf("CopyResult = %q, expected 1/2", got)
+	}
+	if got, _ := p.CopyResult(true); got != `\frac{1}{2}` {
+		t.Errorf("CopyResult LaTeX = %q", got)
+	}
+	if history, _ := p.HistoryEntries(); len(history) != 0 {
+		t.Errorf("Copying a result must not write history: %v", history)
+	}
+}
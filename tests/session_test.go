@@ -0,0 +1,102 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"sandra/APG2_SmartCalc_v3.0_Desktop_Go-1/src/internal/presenter"
+)
+
+func TestSessionSaveAndRestore(t *testing.T) {
+	config := testConfig(t)
+	config.SessionDir = filepath.Join(t.TempDir(), "sessions")
+	p := newTestPresenter(t, config)
+
+	p.LoadExpression("2+x")
+	p.MoveCursorLeft()
+	p.SetUseExact(true)
+	p.SetWindowOpen(presenter.WindowPlot, true)
+	p.SetWindowOpen(presenter.WindowCredit, true)
+	p.SetWindowOpen(presenter.WindowHistory, true)
+	p.SetWindowOpen(presenter.WindowHistory, false)
+	p.SetPlotRange(presenter.PlotRange{XMin: -1, XMax: 5, YMin: 0, YMax: 25})
+	p.SetCreditInputs(presenter.CreditInputs{Amount: "100000", Term: "12", Rate: "10", Type: "Annuity"})
+	if err := p.SaveSession(); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+
+	// Новый запуск получает сеанс прошлого
+	restored := newTestPresenter(t, config)
+	session, err := restored.RestoreSession()
+	if err != nil {
+		t.Fatalf("RestoreSession failed: %v", err)
+	}
+	if restored.Display() != "2+x" || restored.State().Cursor != 2 || !session.Exact || restored.CanUndo() {
+		t.Errorf("Restored state = %+v, session = %+v", restored.State(), session)
+	}
+	if !slices.Equal(session.Windows, []string{presenter.WindowCredit, presenter.WindowPlot}) {
+		t.Errorf("Restored windows = %v, expected credit and plot", session.Windows)
+	}
+	if r := restored.PlotRange(); r.XMax != 5 || r.YMax != 25 {
+		t.Errorf("Restored plot range = %+v", r)
+	}
+	if c := restored.CreditInputs(); c.Amount != "100000" || c.Type != "Annuity" {
+		t.Errorf("Restored credit inputs = %+v", c)
+	}
+}
+
+func TestNamedSessions(t *testing.T) {
+	config := testConfig(t)
+	config.SessionDir = t.TempDir()
+	p := newTestPresenter(t, config)
+
+	p.LoadExpression("1+1")
+	if err := p.SaveSessionAs("cable-load"); err != nil {
+		t.Fatal(err)
+	}
+	p.LoadExpression("7*6")
+	if err := p.SaveSessionAs("taxes"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"", "../escape", "two words"} {
+		if err := p.SaveSessionAs(name); err == nil {
+			t.Errorf("SaveSessionAs(%q) succeeded, expected an error", name)
+		}
+	}
+
+	names, err := p.SessionNames()
+	if err != nil || !slices.Equal(names, []string{"cable-load", "taxes"}) {
+		t.Errorf("SessionNames = %v (%v)", names, err)
+	}
+
+	if _, err := p.LoadSession("cable-load"); err != nil {
+		t.Fatal(err)
+	}
+	if p.Display() != "1+1" || p.SessionName() != "cable-load" {
+		t.Errorf("After switching: display %q, session %q", p.Display(), p.SessionName())
+	}
+	if err := p.DeleteSession("cable-load"); err == nil {
+		t.Error("Deleting the current session succeeded, expected an error")
+	}
+	if err := p.DeleteSession("taxes"); err != nil {
+		t.Errorf("DeleteSession failed: %v", err)
+	}
+
+	// Следующий запуск открывает последний выбранный сеанс
+	session, err := newTestPresenter(t, config).RestoreSession()
+	if err != nil || session.Display != "1+1" {
+		t.Errorf("Restored session = %+v (%v), expected cable-load", session, err)
+	}
+
+	// Без каталога сеансы не сохраняются
+	noSessions := presenter.NewPresenter(nil, nil, presenter.Config{})
+	if err := noSessions.SaveSession(); !errors.Is(err, presenter.ErrNoSessions) {
+		t.Errorf("SaveSession without a directory = %v, expected ErrNoSessions", err)
+	}
+	if _, err := os.Stat(filepath.Join(config.SessionDir, "default.json")); !os.IsNotExist(err) {
+		t.Errorf("Unexpected default session file: %v", err)
+	}
+}